@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -21,16 +23,24 @@ func main() {
 
 	defer db.Close()
 
+	// Las versiones con `Context` de los métodos (`PingContext`, `QueryContext`, `ExecContext`) cancelan
+	// la consulta en el servidor de base de datos cuando el contexto termina. En un servidor HTTP se usa
+	// el contexto de la solicitud (`r.Context()`), así una consulta lenta se detiene si el cliente se desconecta.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
 	// Verificar la conexión
-	if error := db.Ping(); error != nil {
+	if error := db.PingContext(ctx); error != nil {
 		log.Fatal("Error al conectarnos a la base de datos")
 	}
 
 	var age int
 	// Obtener varias filas
-	_, err = db.Query("SELECT name FROM users WHERE age = $1", age)
+	rows, err := db.QueryContext(ctx, "SELECT name FROM users WHERE age = $1", age)
 
 	if err != nil {
 		log.Fatal(err)
 	}
+	// Las filas mantienen ocupada una conexión del pool hasta cerrarlas.
+	defer rows.Close()
 }
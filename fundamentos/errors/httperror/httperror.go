@@ -1,13 +1,67 @@
-package main
+// Package httperror define errores HTTP que los manejadores pueden devolver con `return`
+// y una forma consistente de enviarlos al cliente como `application/problem+json`.
+package httperror
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
 )
 
+/*
+* Errores HTTP y Problem Details (RFC 9457)
+
+En Go los errores son valores, así que un manejador HTTP puede devolver un `error` igual que cualquier
+otra función. Lo que falta es decidir cómo se traduce ese error a una respuesta para el cliente.
+
+La RFC 9457 define un formato estándar para describir errores en APIs HTTP con el tipo de contenido
+`application/problem+json`:
+
+	{
+	  "type": "about:blank",
+	  "title": "Bad Request",
+	  "status": 400,
+	  "detail": "el cuerpo de la solicitud no es un JSON válido",
+	  "errors": [{"field": "email", "detail": "es requerido"}]
+	}
+
+- type: URI que identifica el tipo de problema. `about:blank` indica que el código de estado es suficiente.
+- title: Resumen corto del problema (usamos el texto del código de estado).
+- status: El código de estado HTTP.
+- detail: Explicación específica de este error.
+- errors: Extensión con los errores de cada campo (por ejemplo, al validar un formulario).
+*/
+
+// ContentType es el tipo de contenido de las respuestas de error.
+const ContentType = "application/problem+json"
+
+// HTTPError es un error que conoce el código de estado HTTP con el que debe responderse.
 type HTTPError struct {
 	error
 	Code int
+	// Type es la URI que identifica el tipo de problema. Si está vacía se usa "about:blank".
+	Type string
+	// Fields contiene los errores de campos individuales, si los hay.
+	Fields []FieldError
+}
+
+// FieldError describe el problema de un campo concreto de la solicitud.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Problem es el cuerpo de una respuesta `application/problem+json`.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
 }
 
 func New(code int, message string) *HTTPError {
@@ -17,10 +71,133 @@ func New(code int, message string) *HTTPError {
 	}
 }
 
+// Wrap crea un HTTPError a partir de un error existente.
+// El error original se conserva, así que `errors.Is` y `errors.As` siguen funcionando.
+func Wrap(code int, err error) *HTTPError {
+	return &HTTPError{error: err, Code: code}
+}
+
 func NotFound(message string) *HTTPError {
 	return New(http.StatusNotFound, message)
 }
 
-func main() {
+func BadRequest(message string) *HTTPError {
+	return New(http.StatusBadRequest, message)
+}
+
+func Conflict(message string) *HTTPError {
+	return New(http.StatusConflict, message)
+}
+
+// Unwrap devuelve el error original para que funcionen `errors.Is` y `errors.As`.
+func (e *HTTPError) Unwrap() error {
+	return e.error
+}
+
+// WithFields agrega errores de campos al HTTPError y lo devuelve para poder encadenar llamadas.
+func (e *HTTPError) WithFields(fields ...FieldError) *HTTPError {
+	e.Fields = append(e.Fields, fields...)
+	return e
+}
+
+// Problem convierte el HTTPError en el cuerpo que se envía al cliente.
+func (e *HTTPError) Problem() Problem {
+	problemType := e.Type
+	if problemType == "" {
+		problemType = "about:blank"
+	}
 
+	return Problem{
+		Type:   problemType,
+		Title:  http.StatusText(e.Code),
+		Status: e.Code,
+		Detail: e.Error(),
+		Errors: e.Fields,
+	}
 }
+
+// Write envía `err` al cliente como `application/problem+json`.
+//
+// Si `err` es (o envuelve) un *HTTPError se usa su código y mensaje. Cualquier otro error se
+// considera un error interno: se registra con slog y el cliente solo recibe un 500 genérico,
+// así no filtramos detalles internos (rutas de archivos, consultas SQL, etc.).
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		slog.Error("error interno del servidor", "method", r.Method, "path", r.URL.Path, "error", err)
+		httpErr = New(http.StatusInternalServerError, "ocurrió un error interno en el servidor")
+	}
+
+	body, err := json.Marshal(httpErr.Problem())
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	// Evita que el navegador intente adivinar el tipo de contenido.
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpErr.Code)
+	w.Write(append(body, '\n'))
+}
+
+// HandlerFunc es un manejador HTTP que puede devolver un error.
+// Implementa `http.Handler`, así que puede registrarse directamente en un `http.ServeMux`:
+//
+//	mux.Handle("GET /users/{id}", httperror.HandlerFunc(getUser))
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP ejecuta el manejador y, si devuelve un error, lo envía al cliente con Write.
+func (h HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		Write(w, r, err)
+	}
+}
+
+// FromDecodeError traduce un error de `json.Decoder.Decode` a un HTTPError con un mensaje
+// entendible para el cliente, indicando el campo afectado cuando es posible.
+// Normalmente es un 400, salvo cuando el cuerpo supera el límite de `http.MaxBytesReader` (413).
+func FromDecodeError(err error) *HTTPError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return Wrap(http.StatusRequestEntityTooLarge, err).
+			withDetail(fmt.Sprintf("el cuerpo de la solicitud no puede superar los %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &syntaxErr):
+		return Wrap(http.StatusBadRequest, err).withDetail("el cuerpo de la solicitud contiene un JSON mal formado")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return Wrap(http.StatusBadRequest, err).withDetail("el cuerpo de la solicitud contiene un JSON incompleto")
+	case errors.As(err, &typeErr):
+		return Wrap(http.StatusBadRequest, err).
+			withDetail("el cuerpo de la solicitud contiene un valor con un tipo incorrecto").
+			WithFields(FieldError{Field: typeErr.Field, Detail: "debe ser de tipo " + typeErr.Type.String()})
+	case errors.Is(err, io.EOF):
+		return Wrap(http.StatusBadRequest, err).withDetail("el cuerpo de la solicitud no puede estar vacío")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// `encoding/json` no exporta un tipo para este error, solo el mensaje: json: unknown field "nombre"
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return Wrap(http.StatusBadRequest, err).
+			withDetail("el cuerpo de la solicitud contiene un campo desconocido").
+			WithFields(FieldError{Field: field, Detail: "no es un campo permitido"})
+	default:
+		return Wrap(http.StatusBadRequest, err)
+	}
+}
+
+// withDetail reemplaza el mensaje que se muestra al cliente conservando el error original.
+func (e *HTTPError) withDetail(detail string) *HTTPError {
+	e.error = &detailError{detail: detail, err: e.error}
+	return e
+}
+
+// detailError muestra un mensaje propio, pero sigue envolviendo el error original.
+type detailError struct {
+	detail string
+	err    error
+}
+
+func (e *detailError) Error() string { return e.detail }
+func (e *detailError) Unwrap() error { return e.err }
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+/*
+* Claves de API
+Cada cliente recibe una clave secreta que envía en la cabecera `X-API-Key`. Las claves se cargan
+desde un archivo JSON al iniciar el servidor:
+
+	[
+	  {"key": "clave-larga-y-aleatoria", "subject": "ci", "roles": ["admin"]},
+	  {"key": "otra-clave", "subject": "importador"}
+	]
+
+Guardamos el hash SHA-256 de cada clave en lugar de la clave: así la búsqueda en el mapa compara
+hashes y el tiempo que tarda no revela cuántos caracteres de una clave adivinada son correctos.
+*/
+
+// APIKeyHeader es la cabecera donde el cliente envía su clave.
+const APIKeyHeader = "X-API-Key"
+
+// APIKey es una clave de API y la identidad a la que pertenece.
+type APIKey struct {
+	Key     string   `json:"key"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+// APIKeys autentica las solicitudes con un conjunto fijo de claves.
+type APIKeys struct {
+	keys map[[sha256.Size]byte]Principal
+}
+
+// NewAPIKeys crea un autenticador con las claves indicadas.
+func NewAPIKeys(keys ...APIKey) *APIKeys {
+	a := &APIKeys{keys: make(map[[sha256.Size]byte]Principal, len(keys))}
+	for _, key := range keys {
+		a.keys[sha256.Sum256([]byte(key.Key))] = Principal{Subject: key.Subject, Roles: key.Roles}
+	}
+	return a
+}
+
+// LoadAPIKeys lee las claves desde un archivo JSON con un arreglo de APIKey.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el archivo de claves: %w", err)
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("el archivo de claves no es válido: %w", err)
+	}
+	for i, key := range keys {
+		if key.Key == "" || key.Subject == "" {
+			return nil, fmt.Errorf("la clave %d del archivo debe tener key y subject", i+1)
+		}
+	}
+	return NewAPIKeys(keys...), nil
+}
+
+func (a *APIKeys) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	principal, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: la clave de API no existe", ErrInvalidCredentials)
+	}
+	return principal, nil
+}
@@ -0,0 +1,123 @@
+// Package auth autentica las solicitudes HTTP con claves de API o tokens firmados (JWT HS256)
+// y autoriza el acceso a cada ruta según los roles del usuario autenticado.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* Autenticación y autorización
+- Autenticación: ¿Quién hace la solicitud? Se verifica una credencial (clave de API, token, etc.)
+y se obtiene la identidad del cliente, a la que llamamos `Principal`.
+- Autorización: ¿Puede hacer lo que pide? Se compara la identidad con lo que exige la ruta,
+en este caso un rol como "admin".
+
+La interfaz `Authenticator` permite cambiar la forma de autenticar sin tocar los manejadores.
+Este paquete incluye dos implementaciones y una forma de combinarlas:
+
+- APIKeys: Claves estáticas enviadas en la cabecera `X-API-Key`, pensadas para scripts y otros servicios.
+- JWT: Tokens firmados con HMAC-SHA256 enviados como `Authorization: Bearer <token>`.
+- Chain: Prueba varios autenticadores en orden hasta encontrar uno cuyas credenciales estén en la solicitud.
+
+La autorización se declara por ruta con `Require`, así en el registro de rutas se ve qué exige cada una:
+
+	authn := auth.Chain{apiKeys, tokens}
+	mux.Handle("GET /users/{id}", getUser)                                   // abierta
+	mux.Handle("POST /users", auth.Require(authn)(createUser))               // cualquier usuario autenticado
+	mux.Handle("DELETE /users/{id}", auth.Require(authn, "admin")(deleteUser)) // solo administradores
+
+- Sin credenciales o con credenciales inválidas la respuesta es 401 (Unauthorized).
+- Con credenciales válidas, pero sin el rol necesario, la respuesta es 403 (Forbidden).
+*/
+
+// RoleAdmin es el rol de los administradores.
+const RoleAdmin = "admin"
+
+var (
+	// ErrNoCredentials indica que la solicitud no contiene credenciales para el autenticador.
+	ErrNoCredentials = errors.New("la solicitud no contiene credenciales")
+	// ErrInvalidCredentials indica que las credenciales no son válidas.
+	ErrInvalidCredentials = errors.New("las credenciales no son válidas")
+)
+
+// Principal es la identidad del cliente autenticado.
+type Principal struct {
+	// Subject identifica al cliente, por ejemplo un nombre de usuario o de servicio.
+	Subject string
+	Roles   []string
+}
+
+// HasRole indica si el cliente tiene el rol indicado.
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// Authenticator obtiene la identidad del cliente a partir de las credenciales de la solicitud.
+//
+// Si la solicitud no trae las credenciales que el autenticador entiende devuelve `ErrNoCredentials`;
+// si las trae, pero no son válidas, devuelve un error que envuelve `ErrInvalidCredentials`.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Chain prueba cada autenticador en orden. El primero que encuentra sus credenciales en la solicitud
+// decide el resultado: si sus credenciales son inválidas no se prueban los siguientes.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) (Principal, error) {
+	for _, authn := range c {
+		principal, err := authn.Authenticate(r)
+		if !errors.Is(err, ErrNoCredentials) {
+			return principal, err
+		}
+	}
+	return Principal{}, ErrNoCredentials
+}
+
+// Definimos un tipo específico para la clave, evitando colisiones en `context.WithValue`.
+type principalKeyType string
+
+const principalKey principalKeyType = "principal"
+
+// NewContext devuelve una copia de `ctx` que contiene al cliente autenticado.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// FromContext devuelve el cliente autenticado guardado por `Require` en el contexto de la solicitud.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(Principal)
+	return principal, ok
+}
+
+// Require devuelve un middleware que solo deja pasar las solicitudes autenticadas por `authn`.
+// Si se indican roles, el cliente debe tener al menos uno de ellos.
+// El manejador siguiente puede obtener al cliente con `FromContext(r.Context())`.
+func Require(authn Authenticator, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return httperror.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			principal, err := authn.Authenticate(r)
+			if err != nil {
+				// La cabecera WWW-Authenticate indica al cliente cómo debe autenticarse (RFC 9110).
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				return httperror.Wrap(http.StatusUnauthorized, err)
+			}
+
+			if len(roles) > 0 && !slices.ContainsFunc(roles, principal.HasRole) {
+				return httperror.New(http.StatusForbidden,
+					fmt.Sprintf("se requiere el rol %s", strings.Join(roles, " o ")))
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+			return nil
+		})
+	}
+}
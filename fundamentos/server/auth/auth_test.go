@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// now es la hora simulada de las pruebas.
+var now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestJWT devuelve un JWT con el secreto indicado y la hora simulada.
+func newTestJWT(secret string) *JWT {
+	tokens := NewJWT([]byte(secret))
+	tokens.now = func() time.Time { return now }
+	return tokens
+}
+
+// sign firma un token con `tokens`, o falla la prueba.
+func sign(t *testing.T, tokens *JWT, claims Claims) string {
+	t.Helper()
+
+	token, err := tokens.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+// withHeader reemplaza el encabezado del token y, si `secret` no es vacío, lo vuelve a firmar con HMAC-SHA256.
+// Así se construyen tokens con otro algoritmo, como los de los ataques de confusión de algoritmo.
+func withHeader(t *testing.T, token string, header jwtHeader, secret string) string {
+	t.Helper()
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	unsigned := encoding.EncodeToString(data) + "." + parts[1]
+	if secret == "" {
+		return unsigned + "."
+	}
+	return unsigned + "." + encoding.EncodeToString(newTestJWT(secret).sign(unsigned))
+}
+
+func TestRequire(t *testing.T) {
+	const secret = "secreto-de-prueba-de-al-menos-32-bytes"
+	tokens := newTestJWT(secret)
+	authn := Chain{
+		NewAPIKeys(
+			APIKey{Key: "clave-admin", Subject: "ci", Roles: []string{RoleAdmin}},
+			APIKey{Key: "clave-lectura", Subject: "importador"},
+		),
+		tokens,
+	}
+
+	valid := Claims{Subject: "mayer", Roles: []string{RoleAdmin}, ExpiresAt: now.Add(time.Hour).Unix()}
+	user := Claims{Subject: "ana", ExpiresAt: now.Add(time.Hour).Unix()}
+	expired := Claims{Subject: "mayer", Roles: []string{RoleAdmin}, ExpiresAt: now.Add(-time.Second).Unix()}
+	noExp := Claims{Subject: "mayer", Roles: []string{RoleAdmin}}
+
+	validToken := sign(t, tokens, valid)
+	parts := strings.Split(validToken, ".")
+	// Los datos de `user` con la firma del token de administrador: la firma no corresponde a los datos.
+	userPayload := strings.Split(sign(t, tokens, user), ".")[1]
+
+	tests := []struct {
+		name          string
+		header, value string
+		roles         []string
+		status        int
+	}{
+		{name: "sin credenciales", status: http.StatusUnauthorized},
+		{name: "esquema distinto de Bearer", header: "Authorization", value: "Basic bWF5ZXI6MTIz", status: http.StatusUnauthorized},
+
+		{name: "clave de API válida", header: APIKeyHeader, value: "clave-lectura", status: http.StatusOK},
+		{name: "clave de API desconocida", header: APIKeyHeader, value: "otra", status: http.StatusUnauthorized},
+		{name: "clave de API sin el rol", header: APIKeyHeader, value: "clave-lectura", roles: []string{RoleAdmin}, status: http.StatusForbidden},
+		{name: "clave de API con el rol", header: APIKeyHeader, value: "clave-admin", roles: []string{RoleAdmin}, status: http.StatusOK},
+
+		{name: "token válido", header: "Authorization", value: "Bearer " + validToken, roles: []string{RoleAdmin}, status: http.StatusOK},
+		{name: "esquema en minúsculas", header: "Authorization", value: "bearer " + validToken, status: http.StatusOK},
+		{name: "token sin el rol", header: "Authorization", value: "Bearer " + sign(t, tokens, user), roles: []string{RoleAdmin}, status: http.StatusForbidden},
+		{name: "token expirado", header: "Authorization", value: "Bearer " + sign(t, tokens, expired), status: http.StatusUnauthorized},
+		{name: "token sin exp", header: "Authorization", value: "Bearer " + sign(t, tokens, noExp), status: http.StatusUnauthorized},
+		{name: "firma con otro secreto", header: "Authorization", value: "Bearer " + sign(t, newTestJWT("otro-secreto"), valid), status: http.StatusUnauthorized},
+		{name: "datos modificados", header: "Authorization", value: "Bearer " + parts[0] + "." + userPayload + "." + parts[2], status: http.StatusUnauthorized},
+		{name: "sin firma", header: "Authorization", value: "Bearer " + parts[0] + "." + parts[1] + ".", status: http.StatusUnauthorized},
+		{name: "formato inválido", header: "Authorization", value: "Bearer no-es-un-jwt", status: http.StatusUnauthorized},
+		{
+			name:   `alg "none"`,
+			header: "Authorization", value: "Bearer " + withHeader(t, validToken, jwtHeader{Alg: "none", Typ: "JWT"}, ""),
+			status: http.StatusUnauthorized,
+		},
+		{
+			// Un token que declara otro algoritmo no se acepta aunque la firma HMAC sea correcta.
+			name:   "alg HS512 con firma HS256",
+			header: "Authorization", value: "Bearer " + withHeader(t, validToken, jwtHeader{Alg: "HS512", Typ: "JWT"}, secret),
+			status: http.StatusUnauthorized,
+		},
+		{
+			// Confusión de algoritmo: un atacante firma con HMAC usando como secreto algo público
+			// (como la clave pública RSA) y declara RS256 para que el servidor elija mal cómo verificar.
+			name:   "alg RS256",
+			header: "Authorization", value: "Bearer " + withHeader(t, validToken, jwtHeader{Alg: "RS256", Typ: "JWT"}, secret),
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			handler := Require(authn, tt.roles...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ := FromContext(r.Context())
+				subject = principal.Subject
+			}))
+
+			req := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			switch tt.status {
+			case http.StatusOK:
+				if subject == "" {
+					t.Error("el manejador no recibió al cliente autenticado en el contexto")
+				}
+			case http.StatusUnauthorized:
+				if rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("falta la cabecera WWW-Authenticate en el 401")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+* JSON Web Tokens (JWT) con HS256
+Un JWT son tres partes en base64url separadas por puntos: `encabezado.datos.firma`.
+
+- Encabezado: {"alg":"HS256","typ":"JWT"} indica el algoritmo de la firma.
+- Datos (claims): La identidad y sus permisos, por ejemplo {"sub":"mayer","roles":["admin"],"exp":1735689600}.
+- Firma: HMAC-SHA256 de `encabezado.datos` con una clave secreta que solo conoce el servidor.
+
+Cualquiera puede leer los datos (no están cifrados), pero nadie puede modificarlos sin conocer el secreto:
+la firma dejaría de coincidir. Así el servidor confía en el token sin guardar sesiones.
+
+Al verificar un token:
+- Solo aceptamos el algoritmo HS256. Aceptar el algoritmo que indica el propio token permitiría
+ataques como `"alg":"none"` (tokens sin firma).
+- Comparamos las firmas con `hmac.Equal`, que tarda lo mismo sin importar dónde difieren los bytes.
+- Rechazamos los tokens sin fecha de expiración (`exp`) o que ya expiraron.
+
+Para emitir un token:
+
+	tokens := auth.NewJWT([]byte(os.Getenv("JWT_SECRET")))
+	token, err := tokens.Sign(auth.Claims{
+		Subject:   "mayer",
+		Roles:     []string{auth.RoleAdmin},
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	})
+*/
+
+// Claims son los datos de un token.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Roles     []string `json:"roles,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// jwtHeader es el encabezado de todos los tokens que emitimos.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// ErrTokenExpired indica que el token ya expiró.
+var ErrTokenExpired = fmt.Errorf("%w: el token expiró", ErrInvalidCredentials)
+
+// JWT firma y verifica tokens HS256 y autentica las solicitudes con `Authorization: Bearer <token>`.
+type JWT struct {
+	secret []byte
+	// now devuelve la hora actual. Es un campo para poder simular el paso del tiempo.
+	now func() time.Time
+}
+
+// NewJWT crea un JWT con la clave secreta indicada. Con HS256 el secreto debería tener al menos 32 bytes.
+func NewJWT(secret []byte) *JWT {
+	return &JWT{secret: secret, now: time.Now}
+}
+
+// encoding es base64url sin relleno (`=`), la codificación que usan los JWT.
+var encoding = base64.RawURLEncoding
+
+// Sign genera un token firmado con los datos indicados. Si `IssuedAt` es 0 se usa la hora actual.
+func (j *JWT) Sign(claims Claims) (string, error) {
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = j.now().Unix()
+	}
+
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	return unsigned + "." + encoding.EncodeToString(j.sign(unsigned)), nil
+}
+
+// Verify comprueba la firma y la expiración del token y devuelve sus datos.
+func (j *JWT) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: el token no tiene el formato de un JWT", ErrInvalidCredentials)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Claims{}, fmt.Errorf("%w: el algoritmo del token debe ser HS256", ErrInvalidCredentials)
+	}
+
+	// Verificamos la firma antes de leer los datos: no confiamos en nada de un token sin firma válida.
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, j.sign(parts[0]+"."+parts[1])) {
+		return Claims{}, fmt.Errorf("%w: la firma del token no es válida", ErrInvalidCredentials)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: los datos del token no son válidos", ErrInvalidCredentials)
+	}
+	if claims.ExpiresAt == 0 || j.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+func (j *JWT) Authenticate(r *http.Request) (Principal, error) {
+	// El esquema de autenticación no distingue mayúsculas: "Bearer" y "bearer" son equivalentes.
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return Principal{}, ErrNoCredentials
+	}
+
+	claims, err := j.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: claims.Subject, Roles: claims.Roles}, nil
+}
+
+// sign calcula la firma HMAC-SHA256 de `unsigned`.
+func (j *JWT) sign(unsigned string) []byte {
+	mac := hmac.New(sha256.New, j.secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+// decodeSegment decodifica una parte base64url del token en `v`.
+func decodeSegment(segment string, v any) error {
+	data, err := encoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* Importación y exportación masiva
+- `POST /users:import` crea muchos usuarios en una sola solicitud. El formato se elige con el `Content-Type`:
+  - application/json: un arreglo JSON `[{"name": "...", "email": "..."}, ...]`.
+  - application/x-ndjson: un usuario JSON por línea (newline-delimited JSON).
+  - text/csv: una fila de encabezados (name,email) y un usuario por fila.
+- Cada fila se decodifica, valida y guarda por separado: una fila inválida no impide crear las demás.
+La respuesta es un reporte con el resultado de cada fila (el ID creado o el problema encontrado).
+Si el cuerpo completo es inválido (un arreglo JSON mal formado o con datos después del `]`) la respuesta
+es un 400; las filas leídas antes del error ya se crearon y el mensaje indica cuántas.
+- El cuerpo admite como máximo 10 MB y 10.000 filas; si se supera cualquiera de los dos límites la respuesta es un 413.
+
+- `GET /users:export?format=json|ndjson|csv` devuelve todos los usuarios en el formato indicado.
+La respuesta se escribe por lotes mientras se leen del almacenamiento, así no necesitamos tener
+toda la colección codificada en memoria. Los lotes se piden por clave (`AfterID`) en lugar de por
+`offset`: si se crea o elimina un usuario durante la exportación, no se repiten ni se saltan usuarios.
+
+Los campos `id` y `version` se aceptan al importar, pero se ignoran. Así un archivo exportado se puede
+volver a importar directamente:
+
+	curl -s localhost:8080/users:export?format=csv > users.csv
+	curl -s -H 'Content-Type: text/csv' --data-binary @users.csv localhost:8080/users:import
+
+La importación exige una clave de API (`-H 'X-API-Key: <clave>'`) o un token, salvo si el servidor se inició
+con -insecure-no-auth.
+*/
+
+// maxImportBytes es el tamaño máximo del cuerpo de `POST /users:import` (10 MB).
+const maxImportBytes = 10 << 20
+
+// maxImportRows es la cantidad máxima de filas de `POST /users:import`. Las filas pueden ser muy cortas,
+// así que el límite de bytes por sí solo permitiría cientos de miles de filas en una sola solicitud.
+const maxImportRows = 10_000
+
+// exportBatchSize es la cantidad de usuarios que se leen del almacenamiento en cada lote de la exportación.
+const exportBatchSize = 100
+
+// Formatos de importación y exportación.
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// formatContentTypes asocia cada formato con su tipo de contenido.
+var formatContentTypes = map[string]string{
+	formatJSON:   "application/json",
+	formatNDJSON: "application/x-ndjson",
+	formatCSV:    "text/csv",
+}
+
+// csvHeader son las columnas del CSV exportado. Al importar solo se requieren name y email.
+var csvHeader = []string{"id", "version", "name", "email"}
+
+// ImportReport es la respuesta de `POST /users:import`.
+type ImportReport struct {
+	Created int         `json:"created"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// ImportRow es el resultado de importar una fila. Las filas se numeran desde 1 (sin contar el encabezado CSV).
+type ImportRow struct {
+	Row   int                `json:"row"`
+	ID    int                `json:"id,omitempty"`
+	Error *httperror.Problem `json:"error,omitempty"`
+}
+
+func (a *app) importUsers(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	rows, err := readUsers(r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		return err
+	}
+
+	report := ImportReport{Rows: []ImportRow{}}
+	for user, err := range rows {
+		// Si el cliente se desconectó, dejamos de crear usuarios: nadie recibirá el reporte.
+		if ctxErr := r.Context().Err(); ctxErr != nil {
+			return storeError(ctxErr)
+		}
+		// El cuerpo completo es inválido (por ejemplo, hay datos después del arreglo JSON): respondemos
+		// con ese error en lugar del reporte, indicando cuántos usuarios de las filas anteriores se crearon.
+		var bodyErr bodyError
+		if errors.As(err, &bodyErr) {
+			return bodyErr.withCreated(report.Created)
+		}
+		if len(report.Rows) == maxImportRows {
+			return bodyError{httperror.New(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("la importación admite como máximo %d filas", maxImportRows))}.withCreated(report.Created)
+		}
+		row := ImportRow{Row: len(report.Rows) + 1}
+
+		// La fila se crea igual que en `POST /users`: primero se valida y luego se guarda.
+		if err == nil {
+			err = validateBody(user)
+		}
+		if err == nil {
+			user, err = a.store.Create(r.Context(), User{Name: user.Name, Email: user.Email})
+			err = storeError(err)
+		}
+
+		if err != nil {
+			problem := rowProblem(r, err)
+			row.Error = &problem
+			report.Failed++
+		} else {
+			row.ID = user.ID
+			report.Created++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	RenderJSON(w, report)
+	return nil
+}
+
+// rowProblem convierte el error de una fila en el Problem que se incluye en el reporte.
+// Igual que `httperror.Write`, los errores internos se registran y no se muestran al cliente.
+func rowProblem(r *http.Request, err error) httperror.Problem {
+	var httpErr *httperror.HTTPError
+	if !errors.As(err, &httpErr) {
+		slog.Error("error interno del servidor", "method", r.Method, "path", r.URL.Path, "error", err)
+		httpErr = httperror.New(http.StatusInternalServerError, "ocurrió un error interno en el servidor")
+	}
+	return httpErr.Problem()
+}
+
+// bodyError es un error del cuerpo completo y no de una fila. El iterador lo entrega cuando no puede
+// seguir leyendo y `importUsers` responde con él en lugar del reporte.
+type bodyError struct {
+	*httperror.HTTPError
+}
+
+// withCreated agrega al mensaje cuántos usuarios se crearon antes de encontrar el error.
+func (e bodyError) withCreated(created int) *httperror.HTTPError {
+	if created == 0 {
+		return e.HTTPError
+	}
+	return httperror.New(e.Code, fmt.Sprintf("%s (antes del error se crearon %d usuarios)", e.Error(), created))
+}
+
+// readUsers devuelve un iterador sobre los usuarios del cuerpo según su tipo de contenido.
+//
+// Los errores del inicio del cuerpo (tipo de contenido no soportado, un JSON que no es un arreglo o un
+// CSV sin las columnas necesarias) se devuelven de inmediato. Los errores de cada fila se entregan
+// por el iterador junto con la fila; si el error impide seguir leyendo, el iterador termina.
+// Los errores que impiden seguir leyendo se entregan como bodyError: un arreglo JSON mal formado, sin cerrar
+// o con datos después del `]`, un CSV con comillas sin cerrar o un cuerpo que supera el tamaño máximo.
+func readUsers(contentType string, body io.Reader) (iter.Seq2[User, error], error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == formatContentTypes[formatNDJSON] || mediaType == "application/jsonl":
+		return readNDJSON(body), nil
+	case mediaType == formatContentTypes[formatCSV]:
+		return readCSV(body)
+	case isJSONContentType(contentType):
+		return readJSONArray(body)
+	default:
+		return nil, httperror.New(http.StatusUnsupportedMediaType,
+			"el Content-Type debe ser application/json, application/x-ndjson o text/csv")
+	}
+}
+
+// decodeUser decodifica un usuario con las mismas reglas estrictas que DecodeJSON.
+func decodeUser(data []byte) (User, error) {
+	var user User
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&user); err != nil {
+		return User{}, httperror.FromDecodeError(err)
+	}
+	return user, nil
+}
+
+// readJSONArray lee un arreglo JSON elemento por elemento con `Decoder.Token`, sin cargarlo completo.
+func readJSONArray(body io.Reader) (iter.Seq2[User, error], error) {
+	decoder := json.NewDecoder(body)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, httperror.FromDecodeError(err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, httperror.BadRequest("el cuerpo de la solicitud debe ser un arreglo JSON")
+	}
+
+	return func(yield func(User, error) bool) {
+		for decoder.More() {
+			// Leemos cada elemento como JSON crudo: si un elemento tiene un campo desconocido o un tipo
+			// incorrecto, solo falla esa fila y el decodificador puede continuar con la siguiente.
+			// Si el JSON está mal formado el decodificador no puede seguir: el error es del cuerpo completo.
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				yield(User{}, bodyError{httperror.FromDecodeError(err)})
+				return
+			}
+			if !yield(decodeUser(raw)) {
+				return
+			}
+		}
+
+		// Después del último elemento debe venir `]` y luego el fin del cuerpo: `[{...}] basura`
+		// no es un arreglo JSON válido.
+		if _, err := decoder.Token(); err != nil {
+			if errors.Is(err, io.EOF) {
+				// El cuerpo terminó sin cerrar el arreglo.
+				err = io.ErrUnexpectedEOF
+			}
+			yield(User{}, bodyError{httperror.FromDecodeError(err)})
+			return
+		}
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			if err == nil {
+				err = errors.New("el cuerpo de la solicitud contiene datos después del arreglo JSON")
+			}
+			yield(User{}, bodyError{httperror.FromDecodeError(err)})
+		}
+	}, nil
+}
+
+// readNDJSON lee un usuario JSON por línea. Las líneas vacías se ignoran.
+func readNDJSON(body io.Reader) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(nil, maxBodyBytes)
+
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if !yield(decodeUser(line)) {
+				return
+			}
+		}
+		// Un error del lector (el cuerpo supera maxImportBytes o una línea supera maxBodyBytes) impide
+		// seguir leyendo: es un error del cuerpo completo y no de la última fila.
+		switch err := scanner.Err(); {
+		case errors.Is(err, bufio.ErrTooLong):
+			yield(User{}, bodyError{httperror.New(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("una línea del cuerpo no puede superar los %d bytes", maxBodyBytes))})
+		case err != nil:
+			yield(User{}, bodyError{httperror.FromDecodeError(err)})
+		}
+	}
+}
+
+// readCSV lee un CSV cuya primera fila contiene los nombres de las columnas.
+// Las columnas name y email son obligatorias; id y version se ignoran.
+func readCSV(body io.Reader) (iter.Seq2[User, error], error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, httperror.BadRequest("el CSV debe comenzar con una fila de encabezados")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvHeader, name) {
+			return nil, httperror.BadRequest("el CSV contiene una columna desconocida").
+				WithFields(httperror.FieldError{Field: name, Detail: "no es un campo permitido"})
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"name", "email"} {
+		if _, ok := columns[name]; !ok {
+			return nil, httperror.BadRequest("el CSV no contiene una columna obligatoria").
+				WithFields(httperror.FieldError{Field: name, Detail: "es requerido"})
+		}
+	}
+
+	return func(yield func(User, error) bool) {
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			// Una fila con una cantidad distinta de columnas solo invalida esa fila.
+			// Cualquier otro error (como comillas sin cerrar o un cuerpo demasiado grande) impide seguir
+			// leyendo: es un error del cuerpo completo.
+			if errors.Is(err, csv.ErrFieldCount) {
+				if !yield(User{}, httperror.FromDecodeError(err)) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(User{}, bodyError{httperror.FromDecodeError(err)})
+				return
+			}
+
+			user := User{Name: record[columns["name"]], Email: record[columns["email"]]}
+			if !yield(user, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+func (a *app) exportUsers(w http.ResponseWriter, r *http.Request) error {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+
+	contentType, ok := formatContentTypes[format]
+	if !ok {
+		return httperror.BadRequest("parámetro de consulta inválido").WithFields(httperror.FieldError{
+			Field:  "format",
+			Detail: "debe ser json, ndjson o csv",
+		})
+	}
+
+	// Pedimos el primer lote antes de escribir la respuesta: si el almacenamiento falla,
+	// todavía podemos responder con un error.
+	batch, err := a.exportBatch(r.Context(), 0)
+	if err != nil {
+		return storeError(err)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=users.%s", format))
+	encoder := newUserEncoder(format, w)
+
+	// `http.ResponseController` envía al cliente lo escrito hasta el momento después de cada lote.
+	controller := http.NewResponseController(w)
+	for len(batch) > 0 {
+		for _, user := range batch {
+			if err := encoder.encode(user); err != nil {
+				// El cliente cerró la conexión: no tiene sentido seguir.
+				return nil
+			}
+		}
+		controller.Flush()
+
+		if batch, err = a.exportBatch(r.Context(), batch[len(batch)-1].ID); err != nil {
+			if r.Context().Err() != nil {
+				// El cliente cerró la conexión: no hay nada que registrar.
+				return nil
+			}
+			// Ya enviamos el código 200 y parte del cuerpo, así que no podemos responder con un error.
+			// Registramos el error e interrumpimos la conexión para que el cliente sepa que la respuesta
+			// está incompleta (`http.ErrAbortHandler` no se registra como un pánico del servidor).
+			slog.Error("error al exportar los usuarios", "error", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	// Si el cliente cerró la conexión al final, tampoco podemos responderle con un error.
+	encoder.close()
+	return nil
+}
+
+// exportBatch devuelve el siguiente lote de usuarios ordenados por ID con un ID mayor a `afterID`.
+func (a *app) exportBatch(ctx context.Context, afterID int) ([]User, error) {
+	users, _, err := a.store.List(ctx, UserQuery{AfterID: afterID, Limit: exportBatchSize})
+	return users, err
+}
+
+// userEncoder escribe usuarios uno por uno en el formato de exportación.
+type userEncoder struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	count  int
+}
+
+func newUserEncoder(format string, w io.Writer) *userEncoder {
+	e := &userEncoder{format: format, w: w}
+	if format == formatCSV {
+		e.csv = csv.NewWriter(w)
+	}
+	return e
+}
+
+// encode escribe un usuario. En JSON, el primer usuario abre el arreglo y los siguientes se separan por comas.
+func (e *userEncoder) encode(user User) error {
+	defer func() { e.count++ }()
+
+	if e.format == formatCSV {
+		if e.count == 0 {
+			e.csv.Write(csvHeader)
+		}
+		e.csv.Write([]string{strconv.Itoa(user.ID), strconv.Itoa(user.Version), user.Name, user.Email})
+		// `csv.Writer` tiene su propio búfer: lo vaciamos para que cada usuario llegue a la respuesta.
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case e.format == formatNDJSON:
+		data = append(data, '\n')
+	case e.count == 0:
+		data = append([]byte("[\n"), data...)
+	default:
+		data = append([]byte(",\n"), data...)
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+// close termina el documento: cierra el arreglo JSON o escribe el encabezado CSV si no hubo usuarios.
+func (e *userEncoder) close() error {
+	switch {
+	case e.format == formatJSON && e.count == 0:
+		_, err := io.WriteString(e.w, "[]\n")
+		return err
+	case e.format == formatJSON:
+		_, err := io.WriteString(e.w, "\n]\n")
+		return err
+	case e.format == formatCSV && e.count == 0:
+		e.csv.Write(csvHeader)
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
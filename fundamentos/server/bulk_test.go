@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportJSONArray(t *testing.T) {
+	const user = `{"name":"Mayer","email":"mayer@example.com"}`
+	const other = `{"name":"Ana","email":"ana@example.com"}`
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		created int // usuarios creados según el reporte (solo con 200)
+	}{
+		{name: "arreglo válido", body: "[" + user + ", " + other + "]\n", status: http.StatusOK, created: 2},
+		{name: "arreglo vacío", body: "[]", status: http.StatusOK},
+		{name: "datos después del arreglo", body: "[" + user + "] basura", status: http.StatusBadRequest},
+		{name: "otro valor después del arreglo", body: "[" + user + "] {}", status: http.StatusBadRequest},
+		{name: "arreglo sin cerrar", body: "[" + user, status: http.StatusBadRequest},
+		{name: "no es un arreglo", body: user, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestHandler(t, NewMemoryStore())
+
+			req := httptest.NewRequest(http.MethodPost, "/users:import", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var report ImportReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("reporte inválido: %v", err)
+			}
+			if report.Created != tt.created || report.Failed != 0 {
+				t.Errorf("created = %d, failed = %d; se esperaban %d y 0", report.Created, report.Failed, tt.created)
+			}
+		})
+	}
+}
+
+// TestImportMaxRows verifica que una importación con más de maxImportRows filas responde 413
+// e indica cuántos usuarios se crearon antes del límite.
+func TestImportMaxRows(t *testing.T) {
+	var body strings.Builder
+	for i := range maxImportRows + 1 {
+		fmt.Fprintf(&body, `{"name":"Usuario","email":"u%d@example.com"}`+"\n", i)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users:import", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	newTestHandler(t, NewMemoryStore()).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("estado %d, se esperaba 413: %s", rec.Code, rec.Body)
+	}
+	if want := fmt.Sprintf("se crearon %d usuarios", maxImportRows); !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("cuerpo = %s, se esperaba que indicara %q", rec.Body, want)
+	}
+}
+
+// TestImportReaderErrors verifica que un error al leer el cuerpo NDJSON o CSV hace fallar la solicitud
+// completa en lugar de aparecer como una fila del reporte.
+func TestImportReaderErrors(t *testing.T) {
+	const user = `{"name":"Mayer","email":"mayer@example.com"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{
+			name:        "NDJSON con una línea demasiado larga",
+			contentType: "application/x-ndjson",
+			body:        user + "\n" + strings.Repeat(" ", maxBodyBytes+1) + "\n",
+			status:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "NDJSON que supera el tamaño máximo",
+			contentType: "application/x-ndjson",
+			body:        user + "\n" + strings.Repeat(strings.Repeat(" ", 1<<19)+"\n", maxImportBytes/(1<<19)+1),
+			status:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "CSV que supera el tamaño máximo",
+			contentType: "text/csv",
+			body:        "name,email\nMayer,mayer@example.com\n" + strings.Repeat("\n", maxImportBytes),
+			status:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "CSV con comillas sin cerrar",
+			contentType: "text/csv",
+			body:        "name,email\nMayer,mayer@example.com\n\"Ana,ana@example.com\n",
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users:import", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			newTestHandler(t, NewMemoryStore()).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %.200s", rec.Code, tt.status, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), "se crearon 1 usuarios") {
+				t.Errorf("cuerpo = %.200s, se esperaba que indicara el usuario creado antes del error", rec.Body)
+			}
+		})
+	}
+}
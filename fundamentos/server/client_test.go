@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/server/usersclient"
+)
+
+// newTestClient inicia un servidor de prueba con el manejador completo de la API y devuelve un cliente
+// conectado a él. `wrap` permite envolver el manejador, por ejemplo para simular fallas.
+func newTestClient(t *testing.T, wrap func(http.Handler) http.Handler) *usersclient.Client {
+	t.Helper()
+
+	handler := newTestHandler(t, NewMemoryStore())
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	// Una espera corta entre reintentos para que las pruebas sean rápidas.
+	return usersclient.New(server.URL, usersclient.WithHTTPClient(server.Client()),
+		usersclient.WithRetries(3, time.Millisecond))
+}
+
+// apiError verifica que `err` sea un *usersclient.Error con el estado indicado y lo devuelve.
+func apiError(t *testing.T, err error, status int) *usersclient.Error {
+	t.Helper()
+
+	var apiErr *usersclient.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, se esperaba un *usersclient.Error", err)
+	}
+	if apiErr.Status != status {
+		t.Fatalf("estado = %d, se esperaba %d (%v)", apiErr.Status, status, apiErr)
+	}
+	return apiErr
+}
+
+func TestClientCRUD(t *testing.T) {
+	client := newTestClient(t, nil)
+	ctx := t.Context()
+
+	created, err := client.Create(ctx, usersclient.User{Name: "Mayer", Email: "mayer@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID == 0 || created.Version == 0 {
+		t.Fatalf("Create devolvió %+v, se esperaba un ID y una versión", created)
+	}
+	if _, err := client.Create(ctx, usersclient.User{Name: "Ana", Email: "ana@test.org"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := client.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got != created {
+		t.Errorf("Get = %+v, se esperaba %+v", got, created)
+	}
+
+	page, err := client.List(ctx, usersclient.ListOptions{EmailDomain: "example.com", Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != 1 || len(page.Users) != 1 || page.Users[0].ID != created.ID {
+		t.Errorf("List = %+v, se esperaba solo el usuario %d", page, created.ID)
+	}
+
+	got.Name = "Mayer Chaparro"
+	updated, err := client.Update(ctx, got)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Name != got.Name || updated.Version <= got.Version {
+		t.Errorf("Update = %+v, se esperaba el nuevo nombre y una versión mayor que %d", updated, got.Version)
+	}
+
+	if err := client.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = client.Get(ctx, created.ID)
+	apiError(t, err, http.StatusNotFound)
+}
+
+func TestClientFieldErrors(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	_, err := client.Create(t.Context(), usersclient.User{Name: "Mayer", Email: "no-es-un-email"})
+	apiErr := apiError(t, err, http.StatusBadRequest)
+
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "email" || apiErr.Errors[0].Detail == "" {
+		t.Errorf("errors = %+v, se esperaba un error en el campo email", apiErr.Errors)
+	}
+}
+
+func TestClientUpdateStaleVersion(t *testing.T) {
+	client := newTestClient(t, nil)
+	ctx := t.Context()
+
+	created, err := client.Create(ctx, usersclient.User{Name: "Mayer", Email: "mayer@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Otro cliente modifica el usuario: la versión que leímos queda desactualizada.
+	first := created
+	first.Name = "Primero"
+	if _, err := client.Update(ctx, first); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	stale := created
+	stale.Name = "Segundo"
+	_, err = client.Update(ctx, stale)
+	apiError(t, err, http.StatusPreconditionFailed)
+
+	got, err := client.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != "Primero" {
+		t.Errorf("Name = %q, la actualización con la versión vieja no debía aplicarse", got.Name)
+	}
+}
+
+// flaky responde 503 a las primeras `failures` solicitudes de cada método y cuenta cuántas recibe.
+type flaky struct {
+	failures int
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (f *flaky) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.calls[r.Method]++
+		fail := f.calls[r.Method] <= f.failures
+		f.mu.Unlock()
+
+		if fail {
+			http.Error(w, "no disponible", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *flaky) count(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func TestClientRetries(t *testing.T) {
+	// Con 2 fallas y 3 reintentos, GET, PUT y DELETE terminan bien en el tercer intento.
+	f := &flaky{failures: 2, calls: make(map[string]int)}
+	client := newTestClient(t, f.wrap)
+	ctx := t.Context()
+
+	// POST no se reintenta: recibe el 503 y el servidor ve una sola solicitud.
+	_, err := client.Create(ctx, usersclient.User{Name: "Mayer", Email: "mayer@example.com"})
+	apiError(t, err, http.StatusServiceUnavailable)
+	if n := f.count(http.MethodPost); n != 1 {
+		t.Fatalf("POST se envió %d veces, se esperaba 1", n)
+	}
+
+	// La segunda falla de POST.
+	_, err = client.Create(ctx, usersclient.User{Name: "Mayer", Email: "mayer@example.com"})
+	apiError(t, err, http.StatusServiceUnavailable)
+
+	created, err := client.Create(ctx, usersclient.User{Name: "Mayer", Email: "mayer@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := client.Get(ctx, created.ID); err != nil {
+		t.Errorf("Get: %v", err)
+	}
+	created.Name = "Mayer Chaparro"
+	if _, err := client.Update(ctx, created); err != nil {
+		t.Errorf("Update: %v", err)
+	}
+	if err := client.Delete(ctx, created.ID); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if n := f.count(method); n != 3 {
+			t.Errorf("%s se envió %d veces, se esperaban 3 (2 fallas y 1 éxito)", method, n)
+		}
+	}
+}
+
+func TestClientRetriesExhausted(t *testing.T) {
+	// Más fallas que reintentos: el cliente se rinde después del intento inicial y 3 reintentos.
+	f := &flaky{failures: 10, calls: make(map[string]int)}
+	client := newTestClient(t, f.wrap)
+
+	_, err := client.Get(t.Context(), 1)
+	apiError(t, err, http.StatusServiceUnavailable)
+	if n := f.count(http.MethodGet); n != 4 {
+		t.Errorf("GET se envió %d veces, se esperaban 4", n)
+	}
+}
+
+// TestClientRetryableErrors verifica qué fallas se reintentan: los errores de red, las respuestas cortadas
+// a la mitad y los 502/503/504 sí; un 500 o una respuesta que no es JSON no, ya que se repetirían igual.
+func TestClientRetryableErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter)
+		calls   int
+	}{
+		{
+			name:    "conexión cerrada sin respuesta",
+			respond: func(w http.ResponseWriter) { panic(http.ErrAbortHandler) },
+			calls:   4,
+		},
+		{
+			name:    "respuesta cortada",
+			respond: func(w http.ResponseWriter) { io.WriteString(w, `{"id":1,"name":`) },
+			calls:   4,
+		},
+		{
+			name:    "502",
+			respond: func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			calls:   4,
+		},
+		{
+			name:    "500",
+			respond: func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			calls:   1,
+		},
+		{
+			name:    "respuesta que no es JSON",
+			respond: func(w http.ResponseWriter) { io.WriteString(w, "<html></html>") },
+			calls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			client := newTestClient(t, func(http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					calls++
+					mu.Unlock()
+					tt.respond(w)
+				})
+			})
+
+			if _, err := client.Get(t.Context(), 1); err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if calls != tt.calls {
+				t.Errorf("GET se envió %d veces, se esperaban %d", calls, tt.calls)
+			}
+		})
+	}
+}
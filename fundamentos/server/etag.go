@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* ETag y concurrencia optimista
+Cada usuario tiene una versión que aumenta en cada modificación. A partir de ella generamos una ETag,
+un identificador de la versión del recurso que se envía en la cabecera `ETag` de la respuesta.
+
+- If-None-Match (GET): El cliente envía la ETag que ya tiene. Si el recurso no cambió respondemos
+304 (Not Modified) sin cuerpo y el cliente reutiliza su copia.
+- If-Match (PUT, PATCH, DELETE): El cliente envía la ETag de la versión que leyó. Si otra solicitud
+modificó el usuario desde entonces, respondemos 412 (Precondition Failed) en lugar de sobrescribir
+los cambios del otro cliente. Si el usuario no existe (o se eliminó mientras tanto) la respuesta también es 412.
+
+A esto se le llama concurrencia optimista: no bloqueamos el recurso mientras el cliente lo edita,
+solo verificamos al guardar que nadie lo haya cambiado.
+*/
+
+// etag devuelve la ETag fuerte de la versión actual del usuario, por ejemplo "3".
+func etag(user User) string {
+	return fmt.Sprintf(`"%d"`, user.Version)
+}
+
+// parseETags separa el valor de una cabecera como `"1", W/"2"` en sus ETags.
+func parseETags(header string) []string {
+	var tags []string
+	for tag := range strings.SplitSeq(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// notModified indica si la cabecera If-None-Match contiene la ETag actual del usuario.
+// If-None-Match usa la comparación débil: se ignora el prefijo `W/`.
+func notModified(r *http.Request, user User) bool {
+	current := etag(user)
+	for _, tag := range parseETags(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion evalúa la cabecera If-Match contra la versión actual del usuario `id`.
+//
+// Devuelve 0 si la cabecera no existe o es `*` (solo exige que el usuario exista), o la versión que
+// el almacenamiento debe verificar al guardar. Si ninguna ETag coincide devuelve un error 412.
+// If-Match usa la comparación fuerte: una ETag débil (`W/"1"`) nunca coincide.
+func (a *app) ifMatchVersion(r *http.Request, id int) (int, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+
+	current, err := a.store.Get(r.Context(), id)
+	if err != nil {
+		return 0, conditionalStoreError(r, err)
+	}
+
+	return ifMatch(header, current)
+}
+
+// conditionalStoreError es igual que storeError, pero si la solicitud tiene If-Match y el usuario no existe
+// responde 412 en lugar de 404: ninguna ETag (ni siquiera `*`) coincide con un recurso que no existe
+// (RFC 9110, sección 13.1.1). Así el cliente sabe que su condición no se cumplió, por ejemplo porque
+// otra solicitud eliminó el usuario que leyó.
+func conditionalStoreError(r *http.Request, err error) error {
+	if r.Header.Get("If-Match") != "" && errors.Is(err, ErrUserNotFound) {
+		return httperror.New(http.StatusPreconditionFailed, "el usuario no existe")
+	}
+	return storeError(err)
+}
+
+// ifMatch compara la cabecera If-Match con el usuario ya obtenido del almacenamiento.
+func ifMatch(header string, current User) (int, error) {
+	if header == "" {
+		return 0, nil
+	}
+
+	for _, tag := range parseETags(header) {
+		if tag == "*" {
+			return 0, nil
+		}
+		if tag == etag(current) {
+			return current.Version, nil
+		}
+	}
+	return 0, httperror.New(http.StatusPreconditionFailed, ErrVersionMismatch.Error())
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// concurrentStore simula otra solicitud que modifica el usuario justo antes del primer Update:
+// entre la lectura y la escritura del manejador, el nombre del usuario cambia a "Concurrente".
+type concurrentStore struct {
+	UserStore
+	done bool
+}
+
+func (s *concurrentStore) Update(ctx context.Context, id int, user User, version int) (User, error) {
+	if !s.done {
+		s.done = true
+		current, err := s.UserStore.Get(ctx, id)
+		if err != nil {
+			return User{}, err
+		}
+		current.Name = "Concurrente"
+		if _, err := s.UserStore.Update(ctx, id, current, current.Version); err != nil {
+			return User{}, err
+		}
+	}
+	return s.UserStore.Update(ctx, id, user, version)
+}
+
+// TestPatchConcurrentUpdate verifica que un PATCH no sobrescribe los cambios que otra solicitud guardó
+// después de que el manejador leyó el usuario.
+func TestPatchConcurrentUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+	}{
+		// Sin If-Match el parche se vuelve a aplicar sobre la versión nueva y se conservan ambos cambios.
+		{name: "sin If-Match", status: http.StatusOK},
+		{name: "If-Match *", ifMatch: "*", status: http.StatusOK},
+		// Con la ETag de la versión leída el cambio concurrente es un conflicto.
+		{name: "If-Match con la versión leída", ifMatch: `"1"`, status: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &concurrentStore{UserStore: NewMemoryStore()}
+			if _, err := store.Create(t.Context(), User{Name: "Mayer", Email: "mayer@example.com"}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(`{"email":"andres@example.com"}`))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			newTestHandler(t, store).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var user User
+			if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+				t.Fatalf("cuerpo inválido: %v", err)
+			}
+			want := User{ID: 1, Version: 3, Name: "Concurrente", Email: "andres@example.com"}
+			if user != want {
+				t.Errorf("usuario = %+v, se esperaba %+v", user, want)
+			}
+		})
+	}
+}
+
+// TestIfMatchMissingUser verifica que con If-Match la respuesta es 412 aunque el usuario no exista:
+// ninguna ETag, ni siquiera `*`, coincide con un recurso inexistente (RFC 9110, sección 13.1.1).
+func TestIfMatchMissingUser(t *testing.T) {
+	handler := newTestHandler(t, NewMemoryStore())
+	const body = `{"name":"Mayer","email":"mayer@example.com"}`
+
+	tests := []struct {
+		method, contentType, ifMatch string
+		status                       int
+	}{
+		{http.MethodPut, "application/json", `"1"`, http.StatusPreconditionFailed},
+		{http.MethodPut, "application/json", "*", http.StatusPreconditionFailed},
+		{http.MethodPut, "application/json", "", http.StatusNotFound},
+		{http.MethodPatch, "application/merge-patch+json", `"1"`, http.StatusPreconditionFailed},
+		{http.MethodPatch, "application/merge-patch+json", "", http.StatusNotFound},
+		{http.MethodDelete, "", "*", http.StatusPreconditionFailed},
+		{http.MethodDelete, "", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" If-Match="+tt.ifMatch, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users/1", strings.NewReader(body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+* Server-Sent Events (SSE)
+En lugar de consultar `GET /users/{id}` cada pocos segundos (polling), el cliente abre una sola
+conexión a `GET /users/events` y el servidor le envía cada cambio en cuanto ocurre.
+SSE es texto plano sobre HTTP (`Content-Type: text/event-stream`) y cada evento tiene esta forma:
+
+	id: m1x2k3p4-7
+	event: updated
+	data: {"id":1,"version":2,"name":"Mayer","email":"mayer@example.com"}
+
+- Los navegadores lo soportan con `new EventSource("/users/events")`, que se reconecta solo.
+- Al reconectarse, el cliente envía la cabecera `Last-Event-ID` con el último id que recibió.
+Guardamos los últimos eventos en un registro acotado (`eventLogSize`) para reenviarle los que se perdió.
+- El id tiene dos partes, `<época>-<número>`. El número empieza en 1 cada vez que inicia el servidor, así
+que solo lo comparamos si la época (generada al iniciar el proceso) coincide: un id de antes de un reinicio
+podría ser mayor que todos los actuales y el cliente no recibiría nada.
+- Si el cliente viene de otro proceso o se perdió eventos que ya salieron del registro, no podemos
+reenviarle todo lo que cambió. Le enviamos un evento `reset` (el cliente debe volver a leer los usuarios
+con `GET /users`) seguido de todos los eventos del registro.
+
+* Publicar sin bloquear:
+- Cada suscriptor tiene un canal con buffer. `publish` nunca espera a un cliente lento: si su buffer
+está lleno, lo desconectamos (cerramos su canal). Al reconectarse con `Last-Event-ID` recupera los
+eventos pendientes desde el registro, así `createUser` nunca queda bloqueado por un cliente.
+- Cuando el cliente cierra la conexión se cancela el contexto de la solicitud y el manejador
+elimina su suscripción.
+*/
+
+// Tipos de eventos.
+const (
+	eventCreated = "created"
+	eventUpdated = "updated"
+	eventDeleted = "deleted"
+	// eventReset indica al cliente que se perdió eventos y debe volver a leer los usuarios.
+	eventReset = "reset"
+)
+
+const (
+	// eventLogSize es la cantidad de eventos que se guardan para reenviarlos al reconectarse.
+	eventLogSize = 1000
+	// subscriberBuffer es la cantidad de eventos pendientes que puede acumular un suscriptor.
+	subscriberBuffer = 64
+	// keepAliveInterval es cada cuánto se envía un comentario para que los proxies no cierren la conexión.
+	keepAliveInterval = 15 * time.Second
+)
+
+// UserEvent es un cambio en un usuario. En los eventos `deleted` el usuario solo tiene el ID.
+type UserEvent struct {
+	ID   uint64
+	Type string
+	User User
+}
+
+// eventBroker guarda los últimos eventos y los reparte entre los suscriptores.
+type eventBroker struct {
+	// epoch identifica a este proceso en los ids de los eventos (ver eventID).
+	epoch  string
+	mu     sync.Mutex
+	lastID uint64
+	// log es un búfer circular: cuando está lleno, cada evento nuevo reemplaza al más antiguo.
+	log         []UserEvent
+	subscribers map[chan UserEvent]struct{}
+	closed      bool
+}
+
+func newEventBroker(size int) *eventBroker {
+	return &eventBroker{
+		// La hora de inicio en base 36 es distinta en cada proceso y produce ids cortos.
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		log:         make([]UserEvent, 0, size),
+		subscribers: make(map[chan UserEvent]struct{}),
+	}
+}
+
+// publish registra un evento y lo envía a todos los suscriptores sin bloquearse.
+func (b *eventBroker) publish(eventType string, user User) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := UserEvent{ID: b.lastID, Type: eventType, User: user}
+	if len(b.log) < cap(b.log) {
+		b.log = append(b.log, event)
+	} else {
+		b.log[int((event.ID-1)%uint64(cap(b.log)))] = event
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// El cliente no está leyendo a tiempo: lo desconectamos en lugar de esperarlo.
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// eventID devuelve el id del evento que se envía al cliente, por ejemplo "m1x2k3p4-7".
+func (b *eventBroker) eventID(event UserEvent) string {
+	return b.epoch + "-" + strconv.FormatUint(event.ID, 10)
+}
+
+// subscribe devuelve los eventos registrados después del evento `lastEventID` (vacío si el cliente no
+// recibió ninguno) y un canal por el que llegarán los siguientes. El canal se cierra si el suscriptor
+// se atrasa o si el broker se cierra.
+//
+// Si `lastEventID` es de otro proceso o los eventos siguientes ya no están en el registro, los pendientes
+// empiezan con un evento `reset` y siguen con todo el registro.
+//
+// Ambos se obtienen dentro del mismo bloqueo, así ningún evento se pierde ni se repite entre
+// los eventos pendientes y los que llegan por el canal.
+func (b *eventBroker) subscribe(lastEventID string) ([]UserEvent, chan UserEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	logged := b.ordered()
+	var lastID uint64
+	reset := false
+	if lastEventID != "" {
+		epoch, id, _ := strings.Cut(lastEventID, "-")
+		n, err := strconv.ParseUint(id, 10, 64)
+		// El número de otro proceso no se puede comparar con los nuestros. Dentro del mismo proceso,
+		// si el evento siguiente al último recibido ya salió del registro, el cliente se perdió eventos.
+		lost := len(logged) > 0 && logged[0].ID > n+1
+		reset = err != nil || epoch != b.epoch || n > b.lastID || lost
+		if !reset {
+			lastID = n
+		}
+	}
+
+	var pending []UserEvent
+	if reset {
+		// El evento `reset` tiene el id anterior al primer evento del registro: si el cliente se reconecta
+		// antes de recibir los siguientes, vuelve a recibir todo el registro.
+		var resetID uint64
+		if len(logged) > 0 {
+			resetID = logged[0].ID - 1
+		}
+		pending = append(pending, UserEvent{ID: resetID, Type: eventReset})
+	}
+	for _, event := range logged {
+		if event.ID > lastID {
+			pending = append(pending, event)
+		}
+	}
+
+	ch := make(chan UserEvent, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return pending, ch
+	}
+	b.subscribers[ch] = struct{}{}
+	return pending, ch
+}
+
+// unsubscribe elimina la suscripción. Si el canal ya fue cerrado por el broker no hace nada.
+func (b *eventBroker) unsubscribe(ch chan UserEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// close desconecta a todos los suscriptores. Se llama al apagar el servidor, ya que
+// `http.Server.Shutdown` espera a que terminen las solicitudes y una conexión SSE nunca termina sola.
+func (b *eventBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// ordered devuelve los eventos del registro del más antiguo al más reciente. Debe llamarse con `mu` bloqueado.
+func (b *eventBroker) ordered() []UserEvent {
+	if len(b.log) < cap(b.log) {
+		return b.log
+	}
+	oldest := int(b.lastID % uint64(cap(b.log)))
+	return append(b.log[oldest:len(b.log):len(b.log)], b.log[:oldest]...)
+}
+
+// eventStore envuelve un UserStore y publica un evento por cada cambio exitoso.
+// Así los eventos se generan sin importar qué manejador modificó los usuarios (incluida la importación).
+//
+// Cada escritura y su evento ocurren dentro de `mu`. El almacenamiento libera su propio bloqueo al terminar
+// la escritura: sin `mu`, otra escritura podría guardarse y publicarse entre ambos pasos, y los clientes
+// recibirían los eventos en un orden distinto al de los cambios (por ejemplo, la versión 3 antes que la 2).
+// Los almacenamientos ya serializan sus escrituras, así que `mu` no reduce la concurrencia de las lecturas.
+type eventStore struct {
+	UserStore
+	events *eventBroker
+	mu     sync.Mutex
+}
+
+func (s *eventStore) Create(ctx context.Context, user User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, err := s.UserStore.Create(ctx, user)
+	if err == nil {
+		s.events.publish(eventCreated, created)
+	}
+	return created, err
+}
+
+func (s *eventStore) Update(ctx context.Context, id int, user User, version int) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, err := s.UserStore.Update(ctx, id, user, version)
+	if err == nil {
+		s.events.publish(eventUpdated, updated)
+	}
+	return updated, err
+}
+
+func (s *eventStore) Delete(ctx context.Context, id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.UserStore.Delete(ctx, id, version)
+	if err == nil {
+		s.events.publish(eventDeleted, User{ID: id})
+	}
+	return err
+}
+
+func (a *app) streamEvents(w http.ResponseWriter, r *http.Request) error {
+	// Si el cliente se está reconectando, enviamos primero los eventos que se perdió.
+	pending, events := a.events.subscribe(r.Header.Get("Last-Event-ID"))
+	defer a.events.unsubscribe(events)
+
+	// El `WriteTimeout` del servidor cerraría la conexión a los pocos segundos.
+	// `http.ResponseController` permite quitar el plazo solo para esta respuesta.
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Evita que proxies como nginx acumulen la respuesta en lugar de enviar cada evento.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range pending {
+		if err := writeEvent(w, a.events.eventID(event), event); err != nil {
+			return nil
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		return nil
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			// El cliente cerró la conexión: el `defer` elimina la suscripción.
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				// El broker nos desconectó (cliente lento o apagado del servidor).
+				// El cliente se reconectará con Last-Event-ID.
+				return nil
+			}
+			if err := writeEvent(w, a.events.eventID(event), event); err != nil {
+				return nil
+			}
+		case <-keepAlive.C:
+			// Las líneas que empiezan con ':' son comentarios y el cliente las ignora.
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return nil
+		}
+	}
+}
+
+// writeEvent escribe un evento con el id indicado en el formato de Server-Sent Events.
+// Los eventos `reset` no tienen usuario: sus datos son un objeto vacío.
+func writeEvent(w http.ResponseWriter, id string, event UserEvent) error {
+	data := []byte("{}")
+	if event.Type != eventReset {
+		var err error
+		if data, err = json.Marshal(event.User); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event.Type, data)
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// eventTypes devuelve el tipo y el número de cada evento, por ejemplo "updated 3".
+func eventTypes(events []UserEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, fmt.Sprintf("%s %d", event.Type, event.ID))
+	}
+	return types
+}
+
+// TestEventBrokerSubscribe verifica qué eventos recibe un cliente según su Last-Event-ID, incluidos los ids
+// de otro proceso (el servidor se reinició) y los que ya salieron del registro.
+func TestEventBrokerSubscribe(t *testing.T) {
+	broker := newEventBroker(3)
+	for i := range 5 {
+		broker.publish(eventUpdated, User{ID: i + 1})
+	}
+	// El registro guarda solo los eventos 3, 4 y 5.
+	id := func(n uint64) string { return broker.eventID(UserEvent{ID: n}) }
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        []string
+	}{
+		{name: "sin Last-Event-ID", lastEventID: "", want: []string{"updated 3", "updated 4", "updated 5"}},
+		{name: "reanuda desde el último recibido", lastEventID: id(3), want: []string{"updated 4", "updated 5"}},
+		{name: "al día", lastEventID: id(5), want: []string{}},
+		{name: "el siguiente ya salió del registro", lastEventID: id(1), want: []string{"reset 2", "updated 3", "updated 4", "updated 5"}},
+		{name: "id de otro proceso", lastEventID: "otro-2", want: []string{"reset 2", "updated 3", "updated 4", "updated 5"}},
+		// Antes de reiniciarse el servidor había llegado a un número mayor que el actual.
+		{name: "número mayor que el último", lastEventID: id(9), want: []string{"reset 2", "updated 3", "updated 4", "updated 5"}},
+		{name: "id con otro formato", lastEventID: "7", want: []string{"reset 2", "updated 3", "updated 4", "updated 5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending, ch := broker.subscribe(tt.lastEventID)
+			defer broker.unsubscribe(ch)
+
+			if got := eventTypes(pending); !slices.Equal(got, tt.want) {
+				t.Errorf("eventos = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEventBrokerEpoch verifica que cada broker (cada proceso) usa una época distinta en los ids.
+func TestEventBrokerEpoch(t *testing.T) {
+	first, second := newEventBroker(1), newEventBroker(1)
+	event := UserEvent{ID: 1}
+	if first.eventID(event) == second.eventID(event) {
+		t.Errorf("ambos brokers generaron el id %q", first.eventID(event))
+	}
+}
+
+// slowPublishStore simula que la primera escritura tarda en volver después de guardar el cambio,
+// mientras una segunda escritura se guarda y vuelve de inmediato.
+type slowPublishStore struct {
+	UserStore
+	committed chan struct{}
+	first     atomic.Bool
+}
+
+func (s *slowPublishStore) Update(ctx context.Context, id int, user User, version int) (User, error) {
+	updated, err := s.UserStore.Update(ctx, id, user, version)
+	if s.first.CompareAndSwap(false, true) {
+		close(s.committed)
+		time.Sleep(50 * time.Millisecond)
+	}
+	return updated, err
+}
+
+// TestEventStoreOrder verifica que los eventos se publican en el mismo orden en que se guardaron los cambios.
+func TestEventStoreOrder(t *testing.T) {
+	ctx := t.Context()
+	inner := &slowPublishStore{UserStore: NewMemoryStore(), committed: make(chan struct{})}
+	broker := newEventBroker(10)
+	store := &eventStore{UserStore: inner, events: broker}
+
+	user, err := store.Create(ctx, User{Name: "Mayer", Email: "mayer@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		if _, err := store.Update(ctx, user.ID, User{Name: "Primero", Email: user.Email}, 0); err != nil {
+			t.Errorf("Update: %v", err)
+		}
+	})
+	<-inner.committed
+	wg.Go(func() {
+		if _, err := store.Update(ctx, user.ID, User{Name: "Segundo", Email: user.Email}, 0); err != nil {
+			t.Errorf("Update: %v", err)
+		}
+	})
+	wg.Wait()
+
+	pending, ch := broker.subscribe("")
+	broker.unsubscribe(ch)
+	var versions []int
+	for _, event := range pending {
+		versions = append(versions, event.User.Version)
+	}
+	if !slices.Equal(versions, []int{1, 2, 3}) {
+		t.Errorf("versiones en el orden de los eventos = %v, se esperaba [1 2 3]", versions)
+	}
+}
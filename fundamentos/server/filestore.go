@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+/*
+* Almacenamiento en archivo (JSON Lines)
+- Cada cambio se agrega como una línea JSON al final del archivo (formato "append-only").
+- Después de escribir, llamamos a `file.Sync()` (fsync) para asegurar que los datos lleguen al disco
+y no se queden en la caché del sistema operativo.
+- Al iniciar, el archivo se lee línea por línea y se "reproducen" los cambios para reconstruir
+el estado en memoria. Así los datos sobreviven a los reinicios del servidor.
+- La secuencia de IDs también se reconstruye: como los registros de usuarios eliminados siguen en el
+archivo, el mayor ID registrado nunca se vuelve a asignar después de un reinicio.
+- Si el proceso se detiene a mitad de una escritura (corte de luz, `kill -9`), la última línea puede
+quedar incompleta. Como cada registro termina en un salto de línea, una última línea sin él nunca se
+confirmó al cliente: la descartamos y recortamos el archivo para que la próxima escritura empiece limpia.
+
+Ejemplo del contenido del archivo:
+
+	{"op":"put","id":1,"user":{"id":1,"version":1,"name":"Mayer","email":"mayer@example.com"}}
+	{"op":"delete","id":1}
+*/
+
+// Operaciones que se registran en el archivo.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// fileRecord representa una línea del archivo JSON Lines.
+type fileRecord struct {
+	Op   string `json:"op"`
+	ID   int    `json:"id"`
+	User *User  `json:"user,omitempty"`
+}
+
+// fileStore guarda cada cambio en un archivo y mantiene una copia en memoria para las lecturas.
+type fileStore struct {
+	// mu serializa las escrituras para que el orden en el archivo coincida con el orden en memoria.
+	mu   sync.Mutex
+	file *os.File
+	mem  *memoryStore
+}
+
+// NewFileStore abre (o crea) el archivo en `path` y reconstruye los usuarios guardados en él.
+func NewFileStore(path string) (*fileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir el archivo de usuarios: %w", err)
+	}
+
+	store := &fileStore{file: file, mem: NewMemoryStore()}
+	if err := store.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// load lee el archivo desde el principio y aplica cada registro sobre el almacenamiento en memoria.
+func (s *fileStore) load() error {
+	reader := bufio.NewReader(s.file)
+	// offset es la posición del final de la última línea completa.
+	var offset int64
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				// Última línea sin salto de línea: una escritura interrumpida. La descartamos.
+				slog.Warn("Se descartó una línea incompleta al final del archivo de usuarios", "linea", line, "bytes", len(data))
+				if err := s.file.Truncate(offset); err != nil {
+					return fmt.Errorf("no se pudo recortar el archivo de usuarios: %w", err)
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error al leer el archivo de usuarios: %w", err)
+		}
+		offset += int64(len(data))
+
+		var record fileRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("línea %d inválida en el archivo de usuarios: %w", line, err)
+		}
+		if err := record.validate(); err != nil {
+			return fmt.Errorf("línea %d inválida en el archivo de usuarios: %w", line, err)
+		}
+		s.apply(record)
+	}
+}
+
+// validate verifica que el registro se pueda aplicar: una operación conocida y, si es "put", con el usuario.
+func (r fileRecord) validate() error {
+	switch r.Op {
+	case opPut:
+		if r.User == nil {
+			return errors.New(`el registro "put" no contiene el usuario`)
+		}
+	case opDelete:
+	default:
+		return fmt.Errorf("operación desconocida %q", r.Op)
+	}
+	return nil
+}
+
+// apply aplica un registro sobre el almacenamiento en memoria. El registro debe ser válido (ver validate).
+func (s *fileStore) apply(record fileRecord) {
+	switch record.Op {
+	case opPut:
+		s.mem.put(record.ID, *record.User)
+	case opDelete:
+		s.mem.remove(record.ID)
+	}
+}
+
+// append escribe un registro al final del archivo y espera a que llegue al disco.
+func (s *fileStore) append(record fileRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("no se pudo escribir en el archivo de usuarios: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("no se pudo sincronizar el archivo de usuarios: %w", err)
+	}
+	return nil
+}
+
+func (s *fileStore) Create(ctx context.Context, user User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Otra escritura pudo tener el bloqueo mucho tiempo (por ejemplo, esperando a `Sync`).
+	// Si mientras tanto el contexto terminó, no escribimos en el archivo.
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	if s.mem.emailTaken(user.Email, 0) {
+		return User{}, ErrEmailTaken
+	}
+
+	// Primero escribimos en el archivo y solo si tuvo éxito actualizamos la memoria.
+	// `mu` garantiza que ninguna otra escritura obtenga el mismo ID mientras tanto.
+	user.ID = s.mem.nextID()
+	user.Version = 1
+	record := fileRecord{Op: opPut, ID: user.ID, User: &user}
+	if err := s.append(record); err != nil {
+		return User{}, err
+	}
+	s.apply(record)
+	return user, nil
+}
+
+func (s *fileStore) Get(ctx context.Context, id int) (User, error) {
+	return s.mem.Get(ctx, id)
+}
+
+func (s *fileStore) List(ctx context.Context, query UserQuery) ([]User, int, error) {
+	return s.mem.List(ctx, query)
+}
+
+func (s *fileStore) Update(ctx context.Context, id int, user User, version int) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	current, err := s.mem.current(id, version)
+	if err != nil {
+		return User{}, err
+	}
+	if s.mem.emailTaken(user.Email, id) {
+		return User{}, ErrEmailTaken
+	}
+
+	user.ID = id
+	user.Version = current.Version + 1
+	record := fileRecord{Op: opPut, ID: id, User: &user}
+	if err := s.append(record); err != nil {
+		return User{}, err
+	}
+	s.apply(record)
+	return user, nil
+}
+
+func (s *fileStore) Delete(ctx context.Context, id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.mem.current(id, version); err != nil {
+		return err
+	}
+
+	record := fileRecord{Op: opDelete, ID: id}
+	if err := s.append(record); err != nil {
+		return err
+	}
+	s.apply(record)
+	return nil
+}
+
+// PingContext verifica que el archivo de usuarios siga abierto y accesible.
+// Se usa como verificación de salud en `/readyz`.
+func (s *fileStore) PingContext(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Stat(); err != nil {
+		return fmt.Errorf("el archivo de usuarios no está disponible: %w", err)
+	}
+	return nil
+}
+
+// Close cierra el archivo de usuarios.
+func (s *fileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
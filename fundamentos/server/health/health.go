@@ -0,0 +1,206 @@
+// Package health expone los endpoints de salud de un servidor HTTP: `/healthz`, `/readyz` y `/version`.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+* Endpoints de salud
+Los orquestadores (Kubernetes, Docker, balanceadores de carga) consultan estos endpoints para saber
+qué hacer con cada instancia del servidor:
+
+- Liveness (`/healthz`): ¿El proceso está vivo? Si falla, el orquestador reinicia el proceso.
+Por eso no debe depender de servicios externos: una base de datos caída no se arregla reiniciando.
+- Readiness (`/readyz`): ¿El servidor puede atender solicitudes? Revisa las dependencias registradas
+(almacenamiento, base de datos, etc.). Si falla, el orquestador deja de enviarle tráfico, pero no lo reinicia.
+- Versión (`/version`): Información de compilación obtenida con `runtime/debug.ReadBuildInfo`
+(versión de Go, módulo y commit de git), útil para saber qué versión está desplegada.
+
+Ejemplo de uso:
+
+	checks := health.New()
+	checks.Register("postgres", health.Ping(db)) // db es un *sql.DB
+	checks.Register("pgx", pool.Ping)             // pool es un *pgxpool.Pool
+	checks.Routes(mux)
+	checks.SetReady(true)
+*/
+
+// checkTimeout es el tiempo máximo que puede tardar cada verificación de `/readyz`.
+const checkTimeout = 2 * time.Second
+
+// Check verifica una dependencia y devuelve un error si no está disponible.
+type Check func(ctx context.Context) error
+
+// Pinger lo implementa cualquier dependencia que pueda verificarse con `PingContext`, como `*sql.DB`.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping crea un Check a partir de un Pinger.
+func Ping(p Pinger) Check {
+	return p.PingContext
+}
+
+// Health guarda las verificaciones registradas y el estado de preparación del servidor.
+type Health struct {
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+
+	// ready indica si el servidor acepta tráfico. Empieza en false hasta que el servidor llama a SetReady.
+	ready atomic.Bool
+}
+
+// New crea un Health sin verificaciones registradas.
+func New() *Health {
+	return &Health{checks: make(map[string]Check)}
+}
+
+// Register agrega una verificación que se ejecutará en cada llamada a `/readyz`.
+func (h *Health) Register(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+// SetReady cambia el estado de preparación. El servidor lo pone en false al comenzar el apagado.
+func (h *Health) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Routes registra `GET /healthz`, `GET /readyz` y `GET /version` en el multiplexor.
+func (h *Health) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.Liveness)
+	mux.HandleFunc("GET /readyz", h.Readiness)
+	mux.HandleFunc("GET /version", Version)
+}
+
+// Status es la respuesta de `/healthz` y `/readyz`.
+type Status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness responde siempre 200 mientras el proceso pueda atender solicitudes.
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Status{Status: "ok"})
+}
+
+// Readiness ejecuta todas las verificaciones y responde 200 si todas pasan o 503 si alguna falla
+// o si el servidor está apagándose.
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, Status{Status: "unavailable"})
+		return
+	}
+
+	results := h.run(r.Context())
+
+	status := Status{Status: "ok", Checks: results}
+	code := http.StatusOK
+	for _, result := range results {
+		if result != "ok" {
+			status.Status = "fail"
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+	writeJSON(w, code, status)
+}
+
+// run ejecuta las verificaciones en paralelo, cada una con su propio tiempo límite,
+// y devuelve "ok" o "fail" para cada una.
+//
+// El mensaje de error solo se registra con slog: `/readyz` es público y el error puede contener
+// detalles internos, como la dirección de la base de datos o la ruta de un archivo.
+func (h *Health) run(ctx context.Context) map[string]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]string, len(h.names))
+	)
+
+	for _, name := range h.names {
+		check := h.checks[name]
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			result := "ok"
+			if err := check(ctx); err != nil {
+				result = "fail"
+				slog.WarnContext(ctx, "Falló una verificación de preparación", "check", name, "error", err)
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+	return results
+}
+
+// BuildInfo es la respuesta de `/version`.
+type BuildInfo struct {
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"vcs_revision,omitempty"`
+	Time      string `json:"vcs_time,omitempty"`
+	Modified  bool   `json:"vcs_modified,omitempty"`
+}
+
+// Version responde con la información de compilación del binario.
+func Version(w http.ResponseWriter, r *http.Request) {
+	// `ReadBuildInfo` devuelve la información que Go incrusta en el binario al compilarlo con módulos.
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, Status{Status: "información de compilación no disponible"})
+		return
+	}
+
+	build := BuildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Main.Path,
+		Version:   info.Main.Version,
+	}
+
+	// Las claves `vcs.*` solo existen si el binario se compiló dentro de un repositorio (por ejemplo git).
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.time":
+			build.Time = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, build)
+}
+
+func writeJSON(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	// Las respuestas de salud siempre deben reflejar el estado actual, nunca una copia en caché.
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
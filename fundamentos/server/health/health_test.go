@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestReadinessHidesCheckErrors verifica que `/readyz` responde "fail" sin mostrar el mensaje de error.
+func TestReadinessHidesCheckErrors(t *testing.T) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+
+	h := New()
+	h.Register("store", func(ctx context.Context) error { return nil })
+	h.Register("postgres", func(ctx context.Context) error {
+		return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	})
+
+	rec := httptest.NewRecorder()
+	h.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("estado %d, se esperaba 503 antes de SetReady", rec.Code)
+	}
+
+	h.SetReady(true)
+	rec = httptest.NewRecorder()
+	h.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("estado %d, se esperaba 503", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Errorf("la respuesta muestra el error de la verificación: %s", rec.Body)
+	}
+
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("cuerpo inválido: %v", err)
+	}
+	want := map[string]string{"store": "ok", "postgres": "fail"}
+	if status.Status != "fail" || status.Checks["store"] != want["store"] || status.Checks["postgres"] != want["postgres"] {
+		t.Errorf("respuesta = %+v, se esperaba status fail y checks %v", status, want)
+	}
+}
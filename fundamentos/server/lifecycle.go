@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+/*
+* Ciclo de vida del servidor
+`http.ListenAndServe()` es útil para ejemplos, pero en producción necesitamos más control:
+
+- Tiempos de espera (timeouts): Sin ellos, un cliente lento puede mantener una conexión abierta
+para siempre y agotar los recursos del servidor.
+- Señales del sistema operativo: Cuando presionamos Ctrl+C (SIGINT) o un orquestador como
+Kubernetes detiene el proceso (SIGTERM), queremos apagar el servidor de forma ordenada.
+- Apagado ordenado (graceful shutdown): `server.Shutdown(ctx)` deja de aceptar conexiones nuevas
+y espera a que terminen las solicitudes en curso, como máximo hasta que venza el contexto.
+- Preparación (readiness): Antes de apagar marcamos el servidor como "no listo" para que los
+balanceadores de carga dejen de enviarle tráfico.
+*/
+
+// config agrupa la configuración del servidor que se recibe por banderas (flags).
+type config struct {
+	addr      string
+	storeKind string
+	dataPath  string
+
+	// apiKeysPath es el archivo JSON con las claves de API (ver paquete auth). Vacío si no se usan.
+	apiKeysPath string
+	// insecureNoAuth permite iniciar el servidor sin claves de API ni JWT_SECRET. Sin esta bandera
+	// el servidor no inicia si no hay credenciales configuradas.
+	insecureNoAuth bool
+	// jwtSecret es el secreto con el que se firman los tokens. Se lee de la variable de entorno
+	// JWT_SECRET y no de una bandera, así no aparece en la lista de procesos del sistema.
+	jwtSecret string
+	// corsOrigins son los orígenes que pueden usar la API desde el navegador (ver middleware.CORS).
+	corsOrigins []string
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	// drainDelay es el tiempo que se espera tras marcar el servidor como no listo,
+	// para que los balanceadores de carga dejen de enviar solicitudes nuevas.
+	drainDelay time.Duration
+	// shutdownTimeout es el tiempo máximo para terminar las solicitudes en curso.
+	shutdownTimeout time.Duration
+}
+
+// newHTTPServer crea un `http.Server` con los tiempos de espera de la configuración.
+func newHTTPServer(cfg config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:    cfg.addr,
+		Handler: handler,
+		// Tiempo máximo para leer las cabeceras. Protege contra clientes que las envían muy lento (Slowloris).
+		ReadHeaderTimeout: 5 * time.Second,
+		// Si el cliente no envía la solicitud completa en este tiempo, el servidor cierra la conexión.
+		ReadTimeout: cfg.readTimeout,
+		// Si el servidor no termina de escribir la respuesta en este tiempo, cierra la conexión.
+		WriteTimeout: cfg.writeTimeout,
+		// Tiempo que una conexión keep-alive puede estar inactiva esperando la siguiente solicitud.
+		IdleTimeout: cfg.idleTimeout,
+	}
+}
+
+// serve inicia `srv` y bloquea hasta que el servidor falla o recibe SIGINT/SIGTERM.
+// Al recibir la señal, marca el servidor como no listo y lo apaga de forma ordenada.
+func (a *app) serve(srv *http.Server, cfg config) error {
+	// `signal.NotifyContext` devuelve un contexto que se cancela al recibir alguna de las señales.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// `Shutdown` espera a que terminen las solicitudes en curso, pero los flujos de eventos no terminan solos:
+	// al comenzar el apagado desconectamos a sus suscriptores.
+	srv.RegisterOnShutdown(a.events.close)
+
+	// Abrimos el puerto antes de iniciar el servidor: si está en uso, el error llega aquí y no después
+	// de marcar el servidor como listo. `ListenAndServe` haría ambas cosas a la vez.
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http" // el mismo valor por defecto que `ListenAndServe`
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("Servidor escuchando", "addr", ln.Addr().String())
+
+	// `Serve` bloquea, así que lo ejecutamos en una goroutine y recibimos su error por un canal.
+	// El canal tiene buffer para que la goroutine no se quede bloqueada si ya no estamos escuchando.
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.Serve(ln)
+	}()
+	// El puerto ya acepta conexiones: desde ahora `/readyz` puede responder 200.
+	a.health.SetReady(true)
+
+	select {
+	case err := <-serverErr:
+		// El servidor se detuvo sin que lo pidiéramos.
+		return err
+	case <-ctx.Done():
+	}
+
+	// Dejamos de escuchar las señales: un segundo Ctrl+C termina el proceso inmediatamente.
+	stop()
+
+	slog.Info("Apagando servidor", "drain_delay", cfg.drainDelay, "timeout", cfg.shutdownTimeout)
+	a.health.SetReady(false)
+	time.Sleep(cfg.drainDelay)
+
+	// Usamos un contexto nuevo porque `ctx` ya fue cancelado.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Si vence el plazo, cerramos a la fuerza las conexiones que quedan.
+		srv.Close()
+		return fmt.Errorf("el apagado no terminó a tiempo: %w", err)
+	}
+
+	// Después de `Shutdown`, `Serve` devuelve `http.ErrServerClosed`, que no es un error real.
+	if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	slog.Info("Servidor apagado correctamente")
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+/*
+* JSON Merge Patch (RFC 7396)
+Permite modificar solo una parte de un recurso enviando un documento JSON con los cambios:
+
+- Las claves presentes en el parche reemplazan a las del documento original.
+- Una clave con valor `null` elimina la clave del documento original.
+- Si el valor es un objeto, los cambios se aplican de forma recursiva.
+- Si el parche no es un objeto (por ejemplo un número o un arreglo), reemplaza todo el documento.
+
+Ejemplo:
+
+	original: {"name":"Mayer","email":"mayer@example.com"}
+	parche:   {"email":"andres@example.com"}
+	resultado: {"name":"Mayer","email":"andres@example.com"}
+*/
+
+// mergePatch aplica el parche `patch` sobre el documento JSON `original` y devuelve el resultado.
+func mergePatch(original, patch []byte) ([]byte, error) {
+	var target, changes any
+	if err := json.Unmarshal(original, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue implementa el algoritmo de la RFC 7396 sobre valores ya decodificados.
+func mergeValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		// Si el parche no es un objeto, reemplaza por completo al valor original.
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergeValue(targetObj[key], value)
+	}
+	return targetObj
+}
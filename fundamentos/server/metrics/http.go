@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/server/middleware"
+)
+
+/*
+* Métricas HTTP
+`HTTP` registra tres métricas por cada solicitud:
+
+- http_requests_total{route, method, status}: Solicitudes atendidas. `status` es la clase del código
+("2xx", "4xx", "5xx"), así el total de series no crece con cada código distinto.
+- http_request_duration_seconds{route, method}: Histograma de la duración de las solicitudes.
+- http_requests_in_flight: Solicitudes en curso.
+
+`route` es el patrón de la ruta sin el método ("/users/{id}"), o "unmatched" si ninguna ruta coincidió.
+Igual que AccessLog, debe envolver al multiplexor sin middlewares intermedios que reemplacen la solicitud
+con `r.WithContext`, ya que el multiplexor guarda el patrón en la solicitud que recibe.
+
+Ejemplos de consultas en Prometheus:
+
+	sum by (route) (rate(http_requests_total{status="5xx"}[5m]))
+	histogram_quantile(0.99, sum by (le, route) (rate(http_request_duration_seconds_bucket[5m])))
+*/
+
+// HTTP devuelve un middleware que registra en `reg` la cantidad, la duración y las solicitudes en curso.
+// Registra sus métricas al llamarse, así que solo puede usarse una vez por registro.
+func HTTP(reg *Registry) middleware.Middleware {
+	requests := reg.Counter("http_requests_total", "Solicitudes HTTP atendidas.", "route", "method", "status")
+	duration := reg.Histogram("http_request_duration_seconds", "Duración de las solicitudes HTTP en segundos.",
+		DefaultBuckets, "route", "method")
+	inFlight := reg.Gauge("http_requests_in_flight", "Solicitudes HTTP en curso.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			wrapped := middleware.NewResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			route := routeLabel(r.Pattern)
+			method := methodLabel(r.Method)
+			requests.Inc(route, method, strconv.Itoa(wrapped.Status()/100)+"xx")
+			duration.Observe(time.Since(start).Seconds(), route, method)
+		})
+	}
+}
+
+// routeLabel quita el método del patrón de la ruta ("GET /users/{id}" → "/users/{id}").
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return strings.TrimSpace(path)
+	}
+	return pattern
+}
+
+// methodLabel agrupa los métodos no estándar en "OTHER": el cliente elige el método
+// y no debe poder crear series nuevas enviando métodos inventados.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
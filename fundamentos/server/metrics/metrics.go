@@ -0,0 +1,395 @@
+// Package metrics guarda métricas del proceso (contadores, medidores e histogramas) y las expone
+// en el formato de texto de Prometheus.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+* Métricas en formato Prometheus
+Prometheus consulta periódicamente (scrape) el endpoint `/metrics` de cada servidor y guarda los valores
+en el tiempo. El formato de texto es una línea por serie, con su nombre, sus etiquetas y su valor:
+
+	# HELP http_requests_total Solicitudes HTTP atendidas.
+	# TYPE http_requests_total counter
+	http_requests_total{route="/users/{id}",method="GET",status="2xx"} 42
+
+Tipos de métricas:
+- Counter (contador): Solo aumenta, por ejemplo la cantidad de solicitudes. Prometheus calcula la
+tasa por segundo con `rate()`. Se reinicia a 0 cuando el proceso se reinicia.
+- Gauge (medidor): Sube y baja, por ejemplo las solicitudes en curso o la memoria usada.
+- Histogram (histograma): Cuenta las observaciones (por ejemplo, duraciones) en cubetas acumulativas
+`le` ("menor o igual que"), más su suma y su cantidad. Con ellas Prometheus estima percentiles
+con `histogram_quantile()`.
+
+* Etiquetas (labels)
+Cada combinación de valores de las etiquetas es una serie distinta. Las etiquetas deben tener pocos
+valores posibles: usar el path (`/users/1`, `/users/2`, ...) o el ID de usuario crearía una serie
+por cada valor y agotaría la memoria. Por eso el middleware HTTP usa el patrón de la ruta.
+
+Ejemplo de uso:
+
+	reg := metrics.NewRegistry()
+	jobs := reg.Counter("jobs_total", "Trabajos procesados.", "result")
+	jobs.Inc("ok")
+	mux.Handle("GET /metrics", reg)
+*/
+
+// DefaultBuckets son los límites de cubeta por defecto de un histograma, en segundos.
+// Son los mismos que usa el cliente oficial de Prometheus para medir duraciones de solicitudes.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ContentType es el tipo de contenido del formato de texto de Prometheus.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// validName es la forma válida de los nombres de métricas y etiquetas.
+var validName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// metric lo implementan todos los tipos de métricas del registro.
+type metric interface {
+	write(b *bytes.Buffer)
+}
+
+// desc describe una métrica: su nombre, su descripción, su tipo y los nombres de sus etiquetas.
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// writeHeader escribe las líneas `# HELP` y `# TYPE` de la métrica.
+func (d *desc) writeHeader(b *bytes.Buffer) {
+	fmt.Fprintf(b, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// Registry guarda las métricas de un proceso. Implementa `http.Handler` para servirlas en `/metrics`.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// NewRegistry crea un registro vacío.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// register agrega una métrica. Los nombres inválidos o repetidos son errores de programación: entra en pánico.
+func (r *Registry) register(d desc, m metric) {
+	if !validName.MatchString(d.name) {
+		panic(fmt.Sprintf("metrics: nombre de métrica inválido %q", d.name))
+	}
+	for _, label := range d.labels {
+		if !validName.MatchString(label) || strings.HasPrefix(label, "__") {
+			panic(fmt.Sprintf("metrics: nombre de etiqueta inválido %q en %s", label, d.name))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.metrics[d.name]; ok {
+		panic(fmt.Sprintf("metrics: la métrica %s ya está registrada", d.name))
+	}
+	r.metrics[d.name] = m
+}
+
+// Counter registra un contador con las etiquetas indicadas.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{newFamily(desc{name: name, help: help, kind: "counter", labels: labels}, func() float64 { return 0 })}
+	r.register(c.desc, c)
+	return c
+}
+
+// Gauge registra un medidor con las etiquetas indicadas.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newFamily(desc{name: name, help: help, kind: "gauge", labels: labels}, func() float64 { return 0 })}
+	r.register(g.desc, g)
+	return g
+}
+
+// Histogram registra un histograma con los límites de cubeta `buckets` (en orden ascendente)
+// y las etiquetas indicadas. Si `buckets` está vacío se usa DefaultBuckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	if !slices.IsSorted(buckets) || slices.Contains(labels, "le") {
+		panic(fmt.Sprintf("metrics: el histograma %s necesita cubetas ordenadas y no puede usar la etiqueta \"le\"", name))
+	}
+	// La cubeta +Inf siempre existe: es la cantidad total de observaciones.
+	buckets = slices.DeleteFunc(slices.Clone(buckets), func(b float64) bool { return math.IsInf(b, 1) })
+
+	d := desc{name: name, help: help, kind: "histogram", labels: labels}
+	h := &Histogram{
+		family:  newFamily(d, func() *histogramValue { return &histogramValue{counts: make([]uint64, len(buckets))} }),
+		buckets: buckets,
+	}
+	r.register(d, h)
+	return h
+}
+
+// GaugeFunc registra un medidor sin etiquetas cuyo valor se obtiene llamando a `fn` en cada consulta.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	d := desc{name: name, help: help, kind: "gauge"}
+	r.register(d, &funcMetric{desc: d, fn: fn})
+}
+
+// CounterFunc registra un contador sin etiquetas cuyo valor se obtiene llamando a `fn` en cada consulta.
+// `fn` debe devolver un valor que solo aumenta, como los contadores de `runtime/metrics`.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	d := desc{name: name, help: help, kind: "counter"}
+	r.register(d, &funcMetric{desc: d, fn: fn})
+}
+
+// ServeHTTP escribe todas las métricas ordenadas por nombre en el formato de texto de Prometheus.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]metric, 0, len(names))
+	slices.Sort(names)
+	for _, name := range names {
+		metrics = append(metrics, r.metrics[name])
+	}
+	r.mu.Unlock()
+
+	// Armamos la respuesta completa antes de enviarla: así cada métrica bloquea sus series el menor tiempo posible.
+	var b bytes.Buffer
+	for _, m := range metrics {
+		m.write(&b)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(b.Bytes())
+}
+
+// series es una combinación de valores de etiquetas y su valor.
+type series[T any] struct {
+	labelValues []string
+	value       T
+}
+
+// family guarda las series de una métrica, una por cada combinación de valores de sus etiquetas.
+type family[T any] struct {
+	desc
+
+	mu       sync.Mutex
+	series   map[string]*series[T]
+	newValue func() T
+}
+
+func newFamily[T any](d desc, newValue func() T) family[T] {
+	return family[T]{desc: d, series: make(map[string]*series[T]), newValue: newValue}
+}
+
+// update llama a `fn` con el valor de la serie de `labelValues`, creándola si no existe.
+func (f *family[T]) update(labelValues []string, fn func(value *T)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s espera %d etiquetas y recibió %d", f.name, len(f.labels), len(labelValues)))
+	}
+	// "\xff" no aparece en texto UTF-8 válido, así ("a,b", "c") y ("a", "b,c") son claves distintas.
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series[T]{labelValues: slices.Clone(labelValues), value: f.newValue()}
+		f.series[key] = s
+	}
+	fn(&s.value)
+}
+
+// each llama a `fn` con cada serie, ordenadas por sus etiquetas para que la salida sea estable.
+func (f *family[T]) each(fn func(labelValues []string, value T)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		fn(s.labelValues, s.value)
+	}
+}
+
+// Counter es un contador: un valor que solo aumenta.
+type Counter struct {
+	family[float64]
+}
+
+// Inc suma 1 a la serie con los valores de etiquetas indicados.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add suma `v` a la serie con los valores de etiquetas indicados. `v` no puede ser negativo.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: el contador %s no puede disminuir", c.name))
+	}
+	c.update(labelValues, func(value *float64) { *value += v })
+}
+
+func (c *Counter) write(b *bytes.Buffer) {
+	c.writeHeader(b)
+	c.each(func(labelValues []string, value float64) {
+		writeSample(b, c.name, c.labels, labelValues, "", "", value)
+	})
+}
+
+// Gauge es un medidor: un valor que sube y baja.
+type Gauge struct {
+	family[float64]
+}
+
+// Set cambia el valor de la serie con los valores de etiquetas indicados.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.update(labelValues, func(value *float64) { *value = v })
+}
+
+// Add suma `v` (positivo o negativo) a la serie con los valores de etiquetas indicados.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.update(labelValues, func(value *float64) { *value += v })
+}
+
+// Inc suma 1 a la serie con los valores de etiquetas indicados.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec resta 1 a la serie con los valores de etiquetas indicados.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *Gauge) write(b *bytes.Buffer) {
+	g.writeHeader(b)
+	g.each(func(labelValues []string, value float64) {
+		writeSample(b, g.name, g.labels, labelValues, "", "", value)
+	})
+}
+
+// histogramValue guarda las observaciones de una serie. `counts[i]` es la cantidad de observaciones
+// de la cubeta i (sin acumular); la salida las acumula como espera Prometheus.
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram cuenta observaciones en cubetas, por ejemplo la duración de las solicitudes.
+type Histogram struct {
+	family[*histogramValue]
+	buckets []float64
+}
+
+// Observe agrega una observación a la serie con los valores de etiquetas indicados.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	// La primera cubeta cuyo límite es mayor o igual que `v`. Si no hay ninguna, solo cuenta en +Inf.
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.update(labelValues, func(value **histogramValue) {
+		hv := *value
+		if i < len(hv.counts) {
+			hv.counts[i]++
+		}
+		hv.count++
+		hv.sum += v
+	})
+}
+
+func (h *Histogram) write(b *bytes.Buffer) {
+	h.writeHeader(b)
+	h.each(func(labelValues []string, value *histogramValue) {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			writeSample(b, h.name+"_bucket", h.labels, labelValues, "le", formatFloat(bound), float64(cumulative))
+		}
+		writeSample(b, h.name+"_bucket", h.labels, labelValues, "le", "+Inf", float64(value.count))
+		writeSample(b, h.name+"_sum", h.labels, labelValues, "", "", value.sum)
+		writeSample(b, h.name+"_count", h.labels, labelValues, "", "", float64(value.count))
+	})
+}
+
+// funcMetric es una métrica sin etiquetas cuyo valor se calcula al consultarla.
+type funcMetric struct {
+	desc
+	fn func() float64
+}
+
+func (m *funcMetric) write(b *bytes.Buffer) {
+	m.writeHeader(b)
+	writeSample(b, m.name, nil, nil, "", "", m.fn())
+}
+
+// writeSample escribe una línea `nombre{etiqueta="valor",...} valor`. `extraLabel` agrega una etiqueta
+// más al final (la usa el histograma para `le`).
+func writeSample(b *bytes.Buffer, name string, labels, labelValues []string, extraLabel, extraValue string, value float64) {
+	b.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", label, escapeLabelValue(labelValues[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+// formatFloat escribe un número como lo espera Prometheus, incluidos `+Inf`, `-Inf` y `NaN`.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// Los valores de las etiquetas escapan `\`, `"` y los saltos de línea; la descripción solo `\` y los saltos.
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"math"
+	rtmetrics "runtime/metrics"
+)
+
+/*
+* Métricas del runtime de Go
+El paquete `runtime/metrics` (Go 1.16) expone estadísticas del runtime con nombres estables, como
+"/sched/goroutines:goroutines". A diferencia de `runtime.ReadMemStats`, leerlas no detiene el programa
+(stop-the-world). La lista completa se obtiene con `rtmetrics.All()` (ver `go doc runtime/metrics`).
+
+`RegisterRuntime` lee cada muestra en el momento de la consulta a `/metrics`.
+*/
+
+// runtimeMetrics relaciona las métricas de `runtime/metrics` con su nombre en Prometheus.
+var runtimeMetrics = []struct {
+	name    string
+	help    string
+	sample  string
+	counter bool
+}{
+	{"go_goroutines", "Cantidad de goroutines existentes.", "/sched/goroutines:goroutines", false},
+	{"go_gomaxprocs", "Cantidad máxima de hilos que ejecutan código Go a la vez (GOMAXPROCS).", "/sched/gomaxprocs:threads", false},
+	{"go_memory_total_bytes", "Memoria total reservada por el runtime de Go.", "/memory/classes/total:bytes", false},
+	{"go_heap_objects_bytes", "Memoria ocupada por objetos del heap, vivos o aún no liberados por el GC.", "/memory/classes/heap/objects:bytes", false},
+	{"go_gc_heap_goal_bytes", "Tamaño del heap al que el GC intenta llegar en el próximo ciclo.", "/gc/heap/goal:bytes", false},
+	{"go_gc_heap_allocs_bytes_total", "Bytes reservados en el heap desde que inició el proceso.", "/gc/heap/allocs:bytes", true},
+	{"go_gc_cycles_total", "Ciclos del recolector de basura (GC) completados.", "/gc/cycles/total:gc-cycles", true},
+}
+
+// RegisterRuntime agrega a `reg` las métricas del runtime de Go: goroutines, GOMAXPROCS, memoria y GC.
+func RegisterRuntime(reg *Registry) {
+	for _, m := range runtimeMetrics {
+		read := func() float64 { return readSample(m.sample) }
+		if m.counter {
+			reg.CounterFunc(m.name, m.help, read)
+		} else {
+			reg.GaugeFunc(m.name, m.help, read)
+		}
+	}
+}
+
+// readSample lee una muestra de `runtime/metrics`. Devuelve NaN si esta versión de Go no la soporta.
+func readSample(name string) float64 {
+	samples := []rtmetrics.Sample{{Name: name}}
+	rtmetrics.Read(samples)
+
+	switch value := samples[0].Value; value.Kind() {
+	case rtmetrics.KindUint64:
+		return float64(value.Uint64())
+	case rtmetrics.KindFloat64:
+		return value.Float64()
+	default:
+		return math.NaN()
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+/*
+* Registro de acceso (access log)
+Por cada solicitud se emite un único registro estructurado con `log/slog` (ver fundamentos/slog):
+
+	level=INFO msg="Solicitud HTTP" request_id=K7V... method=GET route="GET /users/{id}" path=/users/1
+	status=200 bytes=61 duration=312.5µs remote_ip=127.0.0.1
+
+- route: El patrón de la ruta (`r.Pattern`), así todas las solicitudes a "/users/1", "/users/2", etc.
+se agrupan bajo "GET /users/{id}". Está vacío si ninguna ruta coincidió (404).
+- remote_ip: La IP de la conexión. No usamos `X-Forwarded-For` porque cualquier cliente puede enviarla;
+solo es confiable si el servidor está detrás de un proxy que la reemplaza.
+
+* Request ID:
+Cada solicitud recibe un identificador que se devuelve en la cabecera `X-Request-ID` y se guarda en el
+contexto. Así podemos relacionar el registro de acceso con otros registros de la misma solicitud
+(por ejemplo, un pánico) y con los del cliente o de otros servicios. Si la solicitud ya trae un
+`X-Request-ID` válido (lo generó un proxy u otro servicio) lo reutilizamos en lugar de crear otro.
+
+Para obtener `r.Pattern`, AccessLog debe envolver directamente al multiplexor o a middlewares que no
+reemplacen la solicitud con `r.WithContext`: el multiplexor guarda el patrón en la solicitud que recibe.
+*/
+
+// RequestIDHeader es la cabecera que contiene el identificador de la solicitud.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength es el largo máximo de un X-Request-ID recibido del cliente.
+const maxRequestIDLength = 128
+
+// Definimos un tipo específico para la clave, evitando colisiones en `context.WithValue`.
+type requestIDKeyType string
+
+const requestIDKey requestIDKeyType = "requestID"
+
+// RequestIDFromContext devuelve el identificador de la solicitud guardado por AccessLog,
+// o una cadena vacía si no existe.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Logging es AccessLog con el logger por defecto de slog.
+func Logging(next http.Handler) http.Handler {
+	return AccessLog(slog.Default())(next)
+}
+
+// AccessLog devuelve un middleware que asigna un X-Request-ID a cada solicitud y, al terminar,
+// registra una línea con el método, la ruta, el estado, el tamaño, la duración y la IP del cliente.
+// Las respuestas 5xx se registran con nivel Error y el resto con nivel Info.
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				// `rand.Text` (Go 1.24) genera una cadena aleatoria de 26 caracteres en base32.
+				id = rand.Text()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			r = r.WithContext(context.WithValue(r.Context(), requestIDKey, id))
+
+			// Envolvemos el ResponseWriter para conocer el código de estado y el tamaño de la respuesta
+			// (ver response.go), incluido el 200 implícito cuando el manejador solo llama a `Write`.
+			wrapped := NewResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			level := slog.LevelInfo
+			if wrapped.Status() >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			// `LogAttrs` evita convertir cada par clave-valor a `any`, lo que lo hace más eficiente.
+			logger.LogAttrs(r.Context(), level, "Solicitud HTTP",
+				slog.String("request_id", id),
+				slog.String("method", r.Method),
+				slog.String("route", r.Pattern),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", wrapped.Status()),
+				slog.Int64("bytes", wrapped.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_ip", remoteIP(r)),
+			)
+		})
+	}
+}
+
+// validRequestID indica si un X-Request-ID recibido puede reutilizarse: no vacío, de largo razonable
+// y solo con caracteres ASCII visibles, para que no pueda inyectar saltos de línea en los registros.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// remoteIP devuelve la IP de la conexión, sin el puerto.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,342 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+* Compresión de respuestas
+El cliente indica en `Accept-Encoding` qué codificaciones entiende y cuánto las prefiere (valor `q`):
+
+	Accept-Encoding: gzip, deflate;q=0.5, br;q=0
+
+Si entiende gzip o deflate (ambos en la librería estándar), comprimimos la respuesta, enviamos la cabecera
+`Content-Encoding` y quitamos `Content-Length`, que ya no corresponde al cuerpo comprimido.
+Como la respuesta depende de `Accept-Encoding`, siempre agregamos `Vary: Accept-Encoding` para que los
+cachés no entreguen una respuesta comprimida a un cliente que no la entiende.
+
+No todas las respuestas conviene comprimirlas:
+- Cuerpos pequeños: Los encabezados de gzip ocupan unos 20 bytes, así que un cuerpo de pocos bytes puede
+crecer. Guardamos lo escrito hasta `MinSize` bytes y solo comprimimos si el cuerpo lo alcanza.
+- Contenido ya comprimido: Imágenes, videos, archivos zip, etc. Comprimirlos otra vez gasta CPU sin
+reducir su tamaño.
+- Respuestas sin cuerpo (204, 304), parciales (206) o que ya traen `Content-Encoding`.
+
+* Reutilizar los compresores con sync.Pool
+Crear un `gzip.Writer` reserva cientos de KB de memoria. `sync.Pool` guarda los compresores que ya no se
+usan para reutilizarlos con `Reset` en la siguiente respuesta, en lugar de crear uno por solicitud.
+
+* Flush y streaming
+Si el manejador llama a `Flush` (SSE, exportación por lotes) comprimimos sin esperar a `MinSize` y
+vaciamos el compresor antes de enviar los datos al cliente.
+
+Compress debe ir después de AccessLog en la cadena: el registro de acceso muestra los bytes comprimidos,
+que son los que se enviaron por la red. No modificamos la ETag: las ETags de los usuarios identifican
+la versión del recurso y se comparan con If-Match, sin importar cómo se codificó la respuesta.
+*/
+
+// defaultCompressMinSize es el tamaño mínimo del cuerpo para comprimirlo, si no se indica otro.
+const defaultCompressMinSize = 1024
+
+// CompressOptions configura el middleware Compress.
+type CompressOptions struct {
+	// Level es el nivel de compresión, de `gzip.BestSpeed` (1) a `gzip.BestCompression` (9).
+	// Si es 0 se usa `gzip.DefaultCompression`.
+	Level int
+	// MinSize es el tamaño mínimo del cuerpo en bytes para comprimirlo. Si es 0 se usa 1024.
+	MinSize int
+}
+
+// encoder es el comportamiento común de `gzip.Writer` y `flate.Writer`.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress devuelve un middleware que comprime las respuestas con gzip o deflate según `Accept-Encoding`.
+func Compress(opts CompressOptions) Middleware {
+	if opts.Level == 0 {
+		opts.Level = gzip.DefaultCompression
+	}
+	if opts.Level < gzip.HuffmanOnly || opts.Level > gzip.BestCompression {
+		panic("middleware: nivel de compresión inválido " + strconv.Itoa(opts.Level))
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = defaultCompressMinSize
+	}
+
+	// Los errores de `NewWriterLevel` y `NewWriter` solo ocurren con un nivel inválido, que ya verificamos.
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			zw, _ := gzip.NewWriterLevel(io.Discard, opts.Level)
+			return zw
+		}},
+		"deflate": {New: func() any {
+			fw, _ := flate.NewWriter(io.Discard, opts.Level)
+			return fw
+		}},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			// Las respuestas a HEAD no tienen cuerpo: su Content-Length debe coincidir con el de GET sin comprimir.
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, pool: pools[encoding], minSize: opts.MinSize}
+			next.ServeHTTP(cw, r)
+			// No usamos `defer`: si el manejador entra en pánico, Recover debe poder responder un 500
+			// sin que antes enviemos el cuerpo guardado en el búfer.
+			cw.Close()
+		})
+	}
+}
+
+// negotiateEncoding elige la codificación de la respuesta según `Accept-Encoding`: "gzip", "deflate"
+// o "" si el cliente no acepta ninguna. Con el mismo valor `q` preferimos gzip.
+func negotiateEncoding(acceptEncoding string) string {
+	// -1 indica que la codificación no aparece en la cabecera.
+	gzipQ, deflateQ, anyQ := -1.0, -1.0, -1.0
+
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "deflate":
+			deflateQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+
+	// `*` se aplica a las codificaciones que no aparecen explícitamente.
+	if gzipQ < 0 {
+		gzipQ = anyQ
+	}
+	if deflateQ < 0 {
+		deflateQ = anyQ
+	}
+
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return "gzip"
+	case deflateQ > 0:
+		return "deflate"
+	default:
+		return ""
+	}
+}
+
+// compressWriter guarda el inicio del cuerpo hasta decidir si lo comprime y luego escribe a través
+// del compresor. Envuelve al ResponseWriter de los middlewares anteriores, así AccessLog registra
+// el código de estado y los bytes comprimidos.
+type compressWriter struct {
+	http.ResponseWriter
+
+	encoding string
+	pool     *sync.Pool
+	minSize  int
+
+	status  int    // código de estado pendiente de enviar
+	buf     []byte // inicio del cuerpo, mientras no decidimos
+	started bool   // se decidió si comprimir y se enviaron las cabeceras
+	enc     encoder
+}
+
+// Unwrap devuelve el ResponseWriter original. Lo usa `http.ResponseController`.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// WriteHeader guarda el código de estado: las cabeceras se envían cuando sabemos si la respuesta se comprime.
+func (cw *compressWriter) WriteHeader(code int) {
+	// Las respuestas informativas (1xx) se envían de inmediato; no son la respuesta final.
+	if cw.started || (code < 200 && code != http.StatusSwitchingProtocols) {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = code
+	}
+	// Si la respuesta no puede comprimirse no hace falta esperar al cuerpo.
+	if !cw.compressible() {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.minSize {
+			return len(b), nil
+		}
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// start decide si la respuesta se comprime, envía las cabeceras y escribe el cuerpo guardado.
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	header := cw.Header()
+
+	// Si el manejador no indicó el tipo, net/http lo deduce de los primeros bytes del cuerpo.
+	// Lo deducimos nosotros, ya que net/http solo vería los bytes comprimidos.
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if compress && cw.compressible() {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// Los rangos de bytes se referirían al cuerpo sin comprimir.
+		header.Del("Accept-Ranges")
+
+		cw.enc = cw.pool.Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compressible indica si la respuesta puede comprimirse según su estado y sus cabeceras.
+func (cw *compressWriter) compressible() bool {
+	switch cw.status {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent, http.StatusSwitchingProtocols:
+		return false
+	}
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	// Sin tipo todavía (cuerpo vacío o WriteHeader antes de escribir): se deduce al enviar las cabeceras.
+	return contentType == "" || !alreadyCompressed(contentType)
+}
+
+// Close envía el cuerpo guardado (sin comprimir, ya que no alcanzó `MinSize`) o termina el cuerpo
+// comprimido y devuelve el compresor al pool.
+func (cw *compressWriter) Close() error {
+	if !cw.started {
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	// Quitamos la referencia al ResponseWriter para que el pool no lo mantenga en memoria.
+	cw.enc.Reset(io.Discard)
+	cw.pool.Put(cw.enc)
+	cw.enc = nil
+	return err
+}
+
+// Flush envía al cliente lo escrito hasta el momento (implementa `http.Flusher`).
+func (cw *compressWriter) Flush() {
+	cw.FlushError()
+}
+
+// FlushError comprime lo escrito hasta el momento y lo envía al cliente. `http.ResponseController` lo usa si existe.
+func (cw *compressWriter) FlushError() error {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		// Quien llama a Flush está enviando la respuesta por partes: no esperamos a `MinSize`.
+		if err := cw.start(true); err != nil {
+			return err
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack toma el control de la conexión (implementa `http.Hijacker`). La respuesta ya no se comprime.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(cw.ResponseWriter).Hijack()
+	if err == nil {
+		cw.started = true
+	}
+	return conn, rw, err
+}
+
+// alreadyCompressed indica si el tipo de contenido ya está comprimido.
+func alreadyCompressed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	major, _, _ := strings.Cut(mediaType, "/")
+	switch {
+	case mediaType == "image/svg+xml":
+		// SVG es texto (XML) y se comprime bien.
+		return false
+	case major == "image", major == "audio", major == "video":
+		return true
+	}
+
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+		"application/x-bzip2", "application/x-xz", "application/x-7z-compressed", "application/vnd.rar",
+		"application/pdf", "application/octet-stream", "font/woff", "font/woff2":
+		return true
+	}
+	return false
+}
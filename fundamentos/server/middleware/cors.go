@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+* CORS (Cross-Origin Resource Sharing)
+Por seguridad, un navegador no deja que el JavaScript de `https://app.example.com` lea las respuestas
+de otro origen como `https://api.example.com`, a menos que la API lo permita con cabeceras CORS.
+
+- Solicitudes simples (GET, POST con ciertos tipos de contenido): El navegador envía la cabecera `Origin`
+y solo entrega la respuesta al JavaScript si contiene `Access-Control-Allow-Origin` con ese origen.
+- Solicitudes con verificación previa (preflight): Antes de un PUT, DELETE, un `Content-Type: application/json`
+o cabeceras propias, el navegador envía un `OPTIONS` con `Access-Control-Request-Method` y
+`Access-Control-Request-Headers` preguntando si puede hacerla. Respondemos 204 con lo que está permitido.
+
+El preflight debe atenderse antes del multiplexor: las rutas se registran con método ("PUT /users/{id}"),
+así que el multiplexor respondería 405 a un `OPTIONS`. Por eso CORS se aplica a todo el servidor.
+
+CORS no es una protección del servidor: solo le indica al navegador qué puede leer el JavaScript.
+Las solicitudes de curl o de otros servidores no se ven afectadas.
+*/
+
+// CORSOptions configura el middleware CORS.
+type CORSOptions struct {
+	// AllowedOrigins son los orígenes permitidos, por ejemplo "https://app.example.com". "*" permite cualquiera.
+	AllowedOrigins []string
+	// AllowedMethods son los métodos permitidos en el preflight. Por defecto GET, HEAD y POST.
+	AllowedMethods []string
+	// AllowedHeaders son las cabeceras que el JavaScript puede enviar, por ejemplo "Content-Type".
+	AllowedHeaders []string
+	// ExposedHeaders son las cabeceras de la respuesta que el JavaScript puede leer, por ejemplo "ETag".
+	ExposedHeaders []string
+	// AllowCredentials permite enviar cookies y la cabecera Authorization. No puede usarse con "*".
+	AllowCredentials bool
+	// MaxAge es el tiempo que el navegador puede guardar la respuesta del preflight.
+	MaxAge time.Duration
+}
+
+// CORS devuelve un middleware que responde los preflight y agrega las cabeceras CORS a las respuestas
+// de los orígenes permitidos. Las solicitudes de otros orígenes pasan sin cabeceras CORS y el navegador
+// no entrega la respuesta al JavaScript.
+//
+// Devuelve un error si la configuración no es válida: un origen que no tiene la forma
+// "scheme://host[:port]" o AllowCredentials junto con "*".
+func CORS(opts CORSOptions) (Middleware, error) {
+	for _, origin := range opts.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			return nil, err
+		}
+	}
+	anyOrigin := slices.Contains(opts.AllowedOrigins, "*")
+	if anyOrigin && opts.AllowCredentials {
+		// Permitir credenciales desde cualquier origen dejaría a cualquier sitio actuar en nombre del usuario.
+		return nil, errors.New("CORS no permite AllowCredentials con el origen \"*\"")
+	}
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+
+	allowedHeaders := make([]string, len(opts.AllowedHeaders))
+	for i, header := range opts.AllowedHeaders {
+		allowedHeaders[i] = http.CanonicalHeaderKey(header)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// La respuesta depende del origen: los cachés no deben entregarla a otro origen.
+			w.Header().Add("Vary", "Origin")
+
+			if origin == "" || (!anyOrigin && !slices.Contains(opts.AllowedOrigins, origin)) {
+				if preflight {
+					// Sin cabeceras CORS el navegador cancela la solicitud real.
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(opts.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(opts.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			//* Preflight: indicamos si el método y las cabeceras solicitadas están permitidos.
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+			if !slices.Contains(opts.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) ||
+				!headersAllowed(r.Header.Get("Access-Control-Request-Headers"), allowedHeaders) {
+				w.Header().Del("Access-Control-Allow-Origin")
+				w.Header().Del("Access-Control-Allow-Credentials")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ", "))
+			if len(opts.AllowedHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+			}
+			if opts.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}, nil
+}
+
+// validateOrigin verifica que el origen sea "*" o tenga la forma "scheme://host[:port]", que es como lo
+// envía el navegador en la cabecera `Origin`. Un origen con ruta o barra final ("https://app.example.com/")
+// nunca coincidiría.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("origen CORS inválido %q: debe tener la forma scheme://host[:port]", origin)
+	}
+	return nil
+}
+
+// headersAllowed indica si todas las cabeceras de `Access-Control-Request-Headers`
+// (separadas por comas) están permitidas. Las cabeceras no distinguen mayúsculas.
+func headersAllowed(requested string, allowed []string) bool {
+	for header := range strings.SplitSeq(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !slices.Contains(allowed, http.CanonicalHeaderKey(header)) {
+			return false
+		}
+	}
+	return true
+}
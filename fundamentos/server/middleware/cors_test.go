@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CORSOptions
+	}{
+		{"credenciales con *", CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{"sin esquema", CORSOptions{AllowedOrigins: []string{"app.example.com"}}},
+		{"esquema no http", CORSOptions{AllowedOrigins: []string{"ftp://app.example.com"}}},
+		{"barra final", CORSOptions{AllowedOrigins: []string{"https://app.example.com/"}}},
+		{"con ruta", CORSOptions{AllowedOrigins: []string{"https://app.example.com/api"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CORS(tt.opts); err == nil {
+				t.Error("se esperaba un error")
+			}
+		})
+	}
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	cors, err := CORS(CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com", "http://localhost:3000"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders: []string{"Content-Type"},
+	})
+	if err != nil {
+		t.Fatalf("CORS: %v", err)
+	}
+	h := cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name, method, origin, requestMethod string
+		allowOrigin                         string
+	}{
+		{"origen permitido", http.MethodGet, "https://app.example.com", "", "https://app.example.com"},
+		{"otro origen", http.MethodGet, "https://evil.example.com", "", ""},
+		{"preflight permitido", http.MethodOptions, "http://localhost:3000", http.MethodPut, "http://localhost:3000"},
+		{"preflight con método no permitido", http.MethodOptions, "http://localhost:3000", http.MethodDelete, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, se esperaba %q", got, tt.allowOrigin)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* Idempotency-Key
+GET, PUT y DELETE son idempotentes: repetirlos deja el servidor en el mismo estado. POST no lo es:
+si la red falla después de que el servidor creó el usuario pero antes de que llegue la respuesta,
+el cliente no sabe si debe reintentar, y si reintenta crea un duplicado.
+
+Con la cabecera `Idempotency-Key` (borrador de la IETF, usada por APIs como Stripe) el cliente envía
+un identificador único por operación, por ejemplo un UUID, y lo repite en cada reintento:
+
+- Primera solicitud con la clave: se ejecuta normalmente y guardamos la respuesta (estado, cabeceras
+y cuerpo) durante `TTL`.
+- Reintento con la misma clave y el mismo cuerpo: devolvemos la respuesta guardada sin ejecutar el
+manejador otra vez, con la cabecera `Idempotent-Replayed: true`.
+- Reintento mientras la primera todavía se está ejecutando: 409 Conflict. El cliente debe esperar y reintentar.
+- Misma clave con otro cuerpo: 422 Unprocessable Content. Es un error del cliente: reutilizó la clave
+en otra operación.
+
+Las respuestas 5xx no se guardan: la operación falló y el cliente puede reintentarla con la misma clave.
+Las solicitudes sin la cabecera se ejecutan como siempre.
+
+La clave se guarda junto con la ruta (y opcionalmente el cliente, con `Scope`), así la misma clave en
+otra ruta u otro cliente es una operación distinta. Las respuestas se guardan en memoria: con varias
+instancias del servidor habría que guardarlas en un almacenamiento compartido, como Redis.
+
+Como cualquier cliente puede enviar claves nuevas, la memoria usada tiene un límite:
+- `MaxEntries` y `MaxBytes` limitan la cantidad de claves y el total de bytes de las respuestas guardadas.
+Al superarlos se eliminan las claves más antiguas primero, aunque todavía no hayan vencido.
+- Una respuesta más grande que `MaxBytes` no se guarda: el cliente recibe la respuesta normalmente,
+pero un reintento con la misma clave vuelve a ejecutar el manejador.
+*/
+
+// IdempotencyKeyHeader es la cabecera con la clave de idempotencia de la solicitud.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength es el largo máximo de una clave de idempotencia.
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyOptions configura el middleware Idempotency.
+type IdempotencyOptions struct {
+	// TTL es el tiempo que se guarda cada respuesta. Si es 0 se usa 24 horas.
+	TTL time.Duration
+	// MaxBodyBytes es el tamaño máximo del cuerpo de la solicitud. Si es 0 se usa 1 MB.
+	MaxBodyBytes int64
+	// MaxEntries es la cantidad máxima de claves guardadas. Si es 0 se usa 10.000.
+	MaxEntries int
+	// MaxBytes es el total máximo de bytes de los cuerpos de respuesta guardados. Si es 0 se usa 32 MB.
+	MaxBytes int
+	// Scope obtiene el cliente de la solicitud, por ejemplo el usuario autenticado. Si es nil,
+	// la clave solo se combina con la ruta.
+	Scope KeyFunc
+}
+
+// idempotencyEntry es una solicitud con clave de idempotencia: en curso o con su respuesta guardada.
+type idempotencyEntry struct {
+	key         string
+	element     *list.Element     // posición en `idempotencyStore.order`
+	fingerprint [sha256.Size]byte // hash del cuerpo de la solicitud
+	done        bool
+	expires     time.Time
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+// idempotencyStore guarda las solicitudes de un middleware Idempotency.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	// order contiene las entradas de la más antigua a la más reciente. Como todas duran `ttl`,
+	// también están ordenadas por vencimiento.
+	order      *list.List
+	bytes      int // total de bytes de los cuerpos guardados
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int
+}
+
+// Idempotency devuelve un middleware que aplica la cabecera Idempotency-Key a las solicitudes.
+// Debe ir después de la autenticación si se usa `Scope` con el usuario autenticado.
+func Idempotency(opts IdempotencyOptions) Middleware {
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10_000
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 32 << 20
+	}
+	store := &idempotencyStore{
+		entries:    make(map[string]*idempotencyEntry),
+		order:      list.New(),
+		ttl:        opts.TTL,
+		maxEntries: opts.MaxEntries,
+		maxBytes:   opts.MaxBytes,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !validIdempotencyKey(key) {
+				httperror.Write(w, r, httperror.BadRequest("la cabecera Idempotency-Key debe tener entre 1 y 255 caracteres visibles"))
+				return
+			}
+
+			// Leemos el cuerpo para compararlo con el de la primera solicitud y lo reemplazamos
+			// por una copia, así el manejador puede leerlo normalmente.
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes))
+			if err != nil {
+				// Si el cuerpo supera `MaxBodyBytes`, FromDecodeError responde 413.
+				httperror.Write(w, r, httperror.FromDecodeError(err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// `r.Pattern` identifica la ruta ("POST /users"). Si el middleware se usa fuera del multiplexor
+			// todavía está vacío y usamos el método y el path.
+			route := r.Pattern
+			if route == "" {
+				route = r.Method + " " + r.URL.Path
+			}
+			storeKey := route + "\n" + key
+			if opts.Scope != nil {
+				storeKey = opts.Scope(r) + "\n" + storeKey
+			}
+
+			entry, state := store.begin(storeKey, sha256.Sum256(body), time.Now())
+			switch state {
+			case idempotencyMismatch:
+				httperror.Write(w, r, httperror.New(http.StatusUnprocessableEntity,
+					"la clave de idempotencia ya se usó con un cuerpo distinto"))
+			case idempotencyInFlight:
+				w.Header().Set("Retry-After", "1")
+				httperror.Write(w, r, httperror.Conflict("hay una solicitud en curso con la misma clave de idempotencia"))
+			case idempotencyDone:
+				replay(w, entry)
+			default:
+				store.record(entry, w, r, next)
+			}
+		})
+	}
+}
+
+// idempotencyState es el resultado de buscar una clave de idempotencia.
+type idempotencyState int
+
+const (
+	idempotencyNew      idempotencyState = iota // primera solicitud con la clave: se ejecuta
+	idempotencyInFlight                         // la primera solicitud todavía se está ejecutando (409)
+	idempotencyDone                             // la respuesta está guardada: se repite
+	idempotencyMismatch                         // la clave se usó con otro cuerpo (422)
+)
+
+// begin busca la solicitud de `key`. Si no existe (o venció) la registra como en curso.
+// El estado se decide con `mu` bloqueado, así dos solicitudes simultáneas nunca se ejecutan ambas.
+func (s *idempotencyStore) begin(key string, fingerprint [sha256.Size]byte, now time.Time) (*idempotencyEntry, idempotencyState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	if entry, ok := s.entries[key]; ok {
+		switch {
+		case entry.fingerprint != fingerprint:
+			return nil, idempotencyMismatch
+		case !entry.done:
+			return nil, idempotencyInFlight
+		default:
+			return entry, idempotencyDone
+		}
+	}
+
+	entry := &idempotencyEntry{key: key, fingerprint: fingerprint, expires: now.Add(s.ttl)}
+	entry.element = s.order.PushBack(entry)
+	s.entries[key] = entry
+	s.evict()
+	return entry, idempotencyNew
+}
+
+// record ejecuta el manejador, envía su respuesta al cliente y la guarda en `entry`.
+// Si la respuesta no se puede guardar (5xx, pánico o más grande que `maxBytes`), elimina la clave
+// para permitir reintentos.
+func (s *idempotencyStore) record(entry *idempotencyEntry, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	rec := &recordingWriter{ResponseWriter: w, before: w.Header().Clone(), limit: s.maxBytes}
+
+	saved := false
+	defer func() {
+		if !saved {
+			s.mu.Lock()
+			s.remove(entry)
+			s.mu.Unlock()
+		}
+	}()
+
+	next.ServeHTTP(rec, r)
+	rec.capture()
+
+	if rec.status >= http.StatusInternalServerError || rec.tooLarge {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// La clave pudo eliminarse mientras el manejador se ejecutaba (venció o se desalojó por los límites).
+	if s.entries[entry.key] != entry {
+		return
+	}
+	entry.status = rec.status
+	entry.header = rec.header
+	entry.body = rec.body.Bytes()
+	entry.done = true
+	s.bytes += len(entry.body)
+	s.evict()
+	saved = true
+}
+
+// sweep elimina las entradas vencidas. Como `order` está ordenada por vencimiento, basta con
+// recorrerla desde el principio hasta la primera que no venció. Debe llamarse con `mu` bloqueado.
+func (s *idempotencyStore) sweep(now time.Time) {
+	for front := s.order.Front(); front != nil; front = s.order.Front() {
+		entry := front.Value.(*idempotencyEntry)
+		if now.Before(entry.expires) {
+			return
+		}
+		s.remove(entry)
+	}
+}
+
+// evict elimina las entradas más antiguas mientras se superen `maxEntries` o `maxBytes`.
+// Debe llamarse con `mu` bloqueado.
+func (s *idempotencyStore) evict() {
+	for len(s.entries) > s.maxEntries || s.bytes > s.maxBytes {
+		s.remove(s.order.Front().Value.(*idempotencyEntry))
+	}
+}
+
+// remove elimina la entrada si todavía está guardada. Debe llamarse con `mu` bloqueado.
+func (s *idempotencyStore) remove(entry *idempotencyEntry) {
+	if s.entries[entry.key] != entry {
+		return
+	}
+	delete(s.entries, entry.key)
+	s.order.Remove(entry.element)
+	s.bytes -= len(entry.body)
+}
+
+// replay envía una respuesta guardada.
+func replay(w http.ResponseWriter, entry *idempotencyEntry) {
+	for key, values := range entry.header {
+		w.Header()[key] = slices.Clone(values)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(entry.body)))
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+}
+
+// validIdempotencyKey indica si la clave tiene un largo razonable y solo caracteres ASCII visibles.
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := range len(key) {
+		if key[i] < '!' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// recordingWriter envía la respuesta al cliente y a la vez guarda el estado, el cuerpo
+// y las cabeceras que agregó el manejador.
+type recordingWriter struct {
+	http.ResponseWriter
+
+	// before son las cabeceras que los middlewares anteriores agregaron antes de llamar al manejador
+	// (X-Request-ID, RateLimit, etc.). No se guardan: al repetir la respuesta se generan de nuevo.
+	before http.Header
+	header http.Header
+	status int
+	body   bytes.Buffer
+	// limit es el tamaño máximo del cuerpo que se guarda. Si se supera, `tooLarge` es true y el cuerpo
+	// se descarta: la respuesta se envía igual, pero no se guarda.
+	limit    int
+	tooLarge bool
+}
+
+// Unwrap devuelve el ResponseWriter original. Lo usa `http.ResponseController`.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// capture guarda el estado y las cabeceras del manejador la primera vez que se llama.
+// Se llama antes de enviar las cabeceras, ya que los middlewares siguientes (como Compress)
+// pueden agregar otras al enviarlas.
+func (rw *recordingWriter) capture() {
+	if rw.header != nil {
+		return
+	}
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	rw.header = make(http.Header)
+	for key, values := range rw.Header() {
+		if !slices.Equal(values, rw.before[key]) {
+			rw.header[key] = slices.Clone(values)
+		}
+	}
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	// Las respuestas informativas (1xx) no son la respuesta final.
+	if code >= 200 && rw.status == 0 {
+		rw.status = code
+		rw.capture()
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.capture()
+	if !rw.tooLarge {
+		if rw.body.Len()+len(b) > rw.limit {
+			rw.tooLarge = true
+			rw.body = bytes.Buffer{}
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// countingHandler responde `body` y cuenta cuántas veces se ejecutó.
+type countingHandler struct {
+	calls int
+	body  string
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, h.body)
+}
+
+// post envía un POST con la clave de idempotencia indicada y devuelve la respuesta.
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	next := &countingHandler{body: `{"id":1}`}
+	h := Idempotency(IdempotencyOptions{})(next)
+
+	first := post(h, "clave", `{"name":"Mayer"}`)
+	second := post(h, "clave", `{"name":"Mayer"}`)
+
+	if next.calls != 1 {
+		t.Fatalf("el manejador se ejecutó %d veces, se esperaba 1", next.calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("repetición = %d %q, se esperaba %d %q", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("falta Idempotent-Replayed en la repetición")
+	}
+
+	if rec := post(h, "clave", `{"name":"Otro"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("misma clave con otro cuerpo: estado %d, se esperaba 422", rec.Code)
+	}
+}
+
+func TestIdempotencyMaxEntries(t *testing.T) {
+	next := &countingHandler{body: `{"id":1}`}
+	h := Idempotency(IdempotencyOptions{MaxEntries: 2})(next)
+
+	post(h, "a", "")
+	post(h, "b", "")
+	post(h, "c", "") // desaloja "a", la más antigua
+
+	post(h, "c", "")
+	post(h, "b", "")
+	if next.calls != 3 {
+		t.Fatalf("el manejador se ejecutó %d veces, se esperaban 3: b y c debían repetirse", next.calls)
+	}
+
+	post(h, "a", "")
+	if next.calls != 4 {
+		t.Errorf("el manejador se ejecutó %d veces, se esperaban 4: a debía haberse desalojado", next.calls)
+	}
+}
+
+func TestIdempotencyMaxBytes(t *testing.T) {
+	next := &countingHandler{body: strings.Repeat("x", 60)}
+	h := Idempotency(IdempotencyOptions{MaxBytes: 100})(next)
+
+	post(h, "a", "")
+	post(h, "b", "") // 120 bytes en total: desaloja "a"
+	post(h, "b", "")
+	if next.calls != 2 {
+		t.Fatalf("el manejador se ejecutó %d veces, se esperaban 2", next.calls)
+	}
+	post(h, "a", "")
+	if next.calls != 3 {
+		t.Errorf("el manejador se ejecutó %d veces, se esperaban 3: a debía haberse desalojado", next.calls)
+	}
+}
+
+func TestIdempotencyResponseTooLarge(t *testing.T) {
+	body := strings.Repeat("x", 200)
+	next := &countingHandler{body: body}
+	h := Idempotency(IdempotencyOptions{MaxBytes: 100})(next)
+
+	// La respuesta llega completa al cliente aunque no se guarde.
+	if rec := post(h, "a", ""); rec.Body.String() != body {
+		t.Fatalf("cuerpo de %d bytes, se esperaban %d", rec.Body.Len(), len(body))
+	}
+	post(h, "a", "")
+	if next.calls != 2 {
+		t.Errorf("el manejador se ejecutó %d veces, se esperaban 2: la respuesta no debía guardarse", next.calls)
+	}
+}
@@ -1,87 +1,88 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"net/http"
-	"time"
-)
-
-// wrappedWriter es un struct que envuelve http.ResponseWriter y nos permite capturar el código de estado HTTP.
-type wrappedWriter struct {
-	http.ResponseWriter
-	statusCode int
+// Package middleware define un tipo común para los middlewares HTTP, permite componerlos
+// y aplicarlos a rutas individuales o a grupos de rutas de un `http.ServeMux`.
+package middleware
+
+import "net/http"
+
+/*
+* Middlewares
+Un middleware es una función que recibe un manejador y devuelve otro que agrega comportamiento
+antes o después de ejecutarlo: registrar la solicitud, autenticar, comprimir la respuesta, etc.
+
+	func(next http.Handler) http.Handler
+
+Usar siempre la misma firma (`Middleware`) permite combinarlos sin importar quién los escribió.
+
+* Orden de ejecución:
+`Chain(a, b, c)` equivale a `a(b(c(handler)))`: el primer middleware es el más externo.
+Al llegar una solicitud se ejecuta `a`, luego `b`, luego `c` y por último el manejador.
+Al volver, el orden es el inverso: el código de `c` después de `next.ServeHTTP` se ejecuta antes que el de `a`.
+
+	a → b → c → handler → c → b → a
+
+* Grupos de rutas:
+`Group` registra rutas en un `http.ServeMux` aplicándoles los middlewares del grupo. Los subgrupos
+heredan los middlewares de su grupo y agregan los suyos después (más cerca del manejador).
+Los middlewares de una ruta concreta se aplican después de los de su grupo.
+
+	mux := http.NewServeMux()
+	api := middleware.NewGroup(mux, middleware.Logging)
+	api.HandleFunc("GET /users", listUsers)                    // Logging
+	admin := api.Group(requireAdmin)
+	admin.HandleFunc("DELETE /users/{id}", deleteUser)         // Logging → requireAdmin
+	admin.HandleFunc("POST /users:import", importUsers, limit) // Logging → requireAdmin → limit
+
+Los middlewares de un grupo se ejecutan después de que el multiplexor eligió la ruta, así que
+`r.Pattern` ya contiene el patrón. Para aplicar un middleware a todas las solicitudes, incluidas
+las que no coinciden con ninguna ruta (404), se envuelve el multiplexor completo: `Chain(...)(mux)`.
+*/
+
+// Middleware envuelve un manejador para agregarle comportamiento.
+type Middleware func(next http.Handler) http.Handler
+
+// Chain compone varios middlewares en uno solo. El primero es el más externo:
+// `Chain(a, b, c)(h)` es igual a `a(b(c(h)))`. Sin middlewares devuelve el manejador sin cambios.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		// Envolvemos desde el último hacia el primero para que el primero quede por fuera.
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
 }
 
-// WriteHeader sobrescribe el método WriteHeader para capturar el código de estado HTTP.
-func (w *wrappedWriter) WriteHeader(statusCode int) {
-	w.ResponseWriter.WriteHeader(statusCode) // Llama al método original de WriteHeader
-	w.statusCode = statusCode                // Guarda el código de estado para utilizarlo después en los logs
+// Group registra rutas en un `http.ServeMux` aplicándoles los mismos middlewares.
+type Group struct {
+	mux         *http.ServeMux
+	middlewares []Middleware
 }
 
-// Logging es un middleware que registra información sobre cada solicitud HTTP.
-// Toma como parámetro un http.Handler (el siguiente handler en la cadena) y devuelve otro http.Handler.
-func Logging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Capturar el tiempo al inicio de la solicitud.
-		start := time.Now()
-
-		// Creamos un wrappedWriter para interceptar el código de estado HTTP.
-		wrapped := &wrappedWriter{
-			ResponseWriter: w,
-			statusCode:     http.StatusOK, // Por defecto, asumimos que la respuesta es 200 OK
-		}
-
-		// Llamamos al siguiente handler en la cadena con el wrappedWriter y el request.
-		next.ServeHTTP(wrapped, r)
-
-		// Registrar la información relevante después de que la solicitud haya sido procesada.
-		log.Printf("Status: %d, Method: %s, Path: %s, Duration: %d\n",
-			wrapped.statusCode, r.Method, r.URL.Path, time.Since(start).Milliseconds())
-	})
+// NewGroup crea un grupo que registra sus rutas en `mux` con los middlewares indicados.
+func NewGroup(mux *http.ServeMux, middlewares ...Middleware) *Group {
+	return &Group{mux: mux, middlewares: middlewares}
 }
 
-// greet es un handler simple que responde con "Hello World!".
-func greet(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "Hello World!")
+// Group crea un subgrupo que comparte el multiplexor. Sus rutas usan los middlewares de este grupo
+// seguidos de los indicados.
+func (g *Group) Group(middlewares ...Middleware) *Group {
+	// Copiamos el slice para que dos subgrupos del mismo grupo no compartan el arreglo subyacente.
+	return &Group{mux: g.mux, middlewares: append(g.middlewares[:len(g.middlewares):len(g.middlewares)], middlewares...)}
 }
 
-// main configura el servidor HTTP y agrega el middleware de registro a las rutas.
-func main() {
-	// Crear un nuevo enrutador (multiplexor de rutas).
-	router := http.NewServeMux()
-
-	// Registrar la ruta "/example" y asignarle el handler greet.
-	// Nota: El método y ruta deben ser ajustados en el patrón adecuado, en Go esto no se coloca en el HandleFunc directamente.
-	router.HandleFunc("/example", LoggerMiddleware(log.Default(), greet))
-
-	// Configuramos el servidor HTTP.
-	server := &http.Server{
-		Addr:    ":8080", // El servidor escucha en el puerto 8080.
-		Handler: router,  // Aplicamos el middleware Logging al router.
-	}
-
-	// Imprimir mensaje en la consola cuando el servidor esté listo.
-	fmt.Printf("Server listening on port %s\n", server.Addr)
-
-	// Iniciar el servidor.
-	log.Fatal(server.ListenAndServe())
+// Wrap aplica al manejador los middlewares del grupo seguidos de los indicados.
+// Es útil para registrar el manejador con otro enrutador que envuelve al mismo multiplexor.
+func (g *Group) Wrap(handler http.Handler, middlewares ...Middleware) http.Handler {
+	return Chain(g.middlewares...)(Chain(middlewares...)(handler))
 }
 
-// LoggerMiddleware es otro middleware que usa un registrador personalizado para registrar información de la solicitud.
-func LoggerMiddleware(logger *log.Logger, handler http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Capturar el tiempo de inicio de la solicitud.
-		receivedTime := time.Now()
-
-		// Registrar la solicitud recibida.
-		logger.Printf("Request received: Method=%s, Path=%s\n", r.Method, r.URL.Path)
-
-		// Llamar al siguiente handler en la cadena.
-		handler(w, r)
+// Handle registra el manejador con el patrón indicado, aplicándole los middlewares del grupo
+// y después los de la ruta.
+func (g *Group) Handle(pattern string, handler http.Handler, middlewares ...Middleware) {
+	g.mux.Handle(pattern, g.Wrap(handler, middlewares...))
+}
 
-		// Registrar la finalización de la solicitud.
-		logger.Printf("Request complete: Method=%s, Path=%s, Duration=%dms\n",
-			r.Method, r.URL.Path, time.Since(receivedTime).Milliseconds())
-	}
+// HandleFunc es igual que Handle, pero recibe una función.
+func (g *Group) HandleFunc(pattern string, handler http.HandlerFunc, middlewares ...Middleware) {
+	g.Handle(pattern, handler, middlewares...)
 }
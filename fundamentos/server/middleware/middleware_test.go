@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// recorder guarda el orden en que se ejecutan los middlewares y el manejador de una solicitud.
+type recorder struct {
+	calls []string
+}
+
+// mw devuelve un middleware que registra "name>" al entrar y "<name" al volver.
+func (rec *recorder) mw(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec.calls = append(rec.calls, name+">")
+			next.ServeHTTP(w, r)
+			rec.calls = append(rec.calls, "<"+name)
+		})
+	}
+}
+
+// handler devuelve un manejador que registra su nombre.
+func (rec *recorder) handler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.calls = append(rec.calls, name)
+	})
+}
+
+// serve envía una solicitud a `h` y devuelve el orden de ejecución.
+func (rec *recorder) serve(t *testing.T, h http.Handler, method, target string) []string {
+	t.Helper()
+
+	rec.calls = nil
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s %s: estado %d", method, target, w.Code)
+	}
+	return rec.calls
+}
+
+func TestChainOrder(t *testing.T) {
+	rec := &recorder{}
+	h := Chain(rec.mw("a"), rec.mw("b"), rec.mw("c"))(rec.handler("h"))
+
+	want := []string{"a>", "b>", "c>", "h", "<c", "<b", "<a"}
+	if got := rec.serve(t, h, http.MethodGet, "/"); !slices.Equal(got, want) {
+		t.Errorf("orden = %v, se esperaba %v", got, want)
+	}
+}
+
+func TestChainEmpty(t *testing.T) {
+	rec := &recorder{}
+	h := Chain()(rec.handler("h"))
+
+	if got := rec.serve(t, h, http.MethodGet, "/"); !slices.Equal(got, []string{"h"}) {
+		t.Errorf("orden = %v, se esperaba solo el manejador", got)
+	}
+}
+
+func TestGroupOrder(t *testing.T) {
+	rec := &recorder{}
+	mux := http.NewServeMux()
+
+	api := NewGroup(mux, rec.mw("api"))
+	api.Handle("GET /users", rec.handler("list"))
+	admin := api.Group(rec.mw("admin1"), rec.mw("admin2"))
+	admin.Handle("DELETE /users/{id}", rec.handler("delete"), rec.mw("route"))
+	audit := admin.Group(rec.mw("audit"))
+	audit.Handle("POST /users:import", rec.handler("import"), rec.mw("limit"))
+
+	tests := []struct {
+		method, target string
+		want           []string
+	}{
+		{http.MethodGet, "/users", []string{"api>", "list", "<api"}},
+		{http.MethodDelete, "/users/1", []string{
+			"api>", "admin1>", "admin2>", "route>", "delete", "<route", "<admin2", "<admin1", "<api",
+		}},
+		{http.MethodPost, "/users:import", []string{
+			"api>", "admin1>", "admin2>", "audit>", "limit>", "import", "<limit", "<audit", "<admin2", "<admin1", "<api",
+		}},
+	}
+	for _, tt := range tests {
+		if got := rec.serve(t, mux, tt.method, tt.target); !slices.Equal(got, tt.want) {
+			t.Errorf("%s %s: orden = %v, se esperaba %v", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
+
+// TestSiblingGroupsDoNotShareMiddlewares verifica que dos subgrupos del mismo grupo no se pisan
+// sus middlewares. Sin la copia de Group, `append` sobre un slice con capacidad libre escribiría
+// en el mismo arreglo subyacente y el segundo subgrupo reemplazaría el middleware del primero.
+func TestSiblingGroupsDoNotShareMiddlewares(t *testing.T) {
+	rec := &recorder{}
+
+	// El grupo padre tiene capacidad libre, como ocurre tras varios Group anidados.
+	parentMiddlewares := make([]Middleware, 1, 4)
+	parentMiddlewares[0] = rec.mw("parent")
+
+	tests := []struct {
+		name   string
+		parent *Group
+	}{
+		{"capacidad libre", NewGroup(http.NewServeMux(), parentMiddlewares...)},
+		// a+b ocupa 2 de 2; agregar c duplica la capacidad a 4 y deja espacio libre en el nieto.
+		{"grupos anidados", NewGroup(http.NewServeMux(), rec.mw("a")).Group(rec.mw("b")).Group(rec.mw("c"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.parent.Group(rec.mw("first"))
+			second := tt.parent.Group(rec.mw("second"))
+			first.Handle("GET /first", rec.handler("h"))
+			second.Handle("GET /second", rec.handler("h"))
+
+			if got := rec.serve(t, tt.parent.mux, http.MethodGet, "/first"); !slices.Contains(got, "first>") || slices.Contains(got, "second>") {
+				t.Errorf("GET /first: orden = %v, se esperaba solo el middleware first", got)
+			}
+			if got := rec.serve(t, tt.parent.mux, http.MethodGet, "/second"); !slices.Contains(got, "second>") || slices.Contains(got, "first>") {
+				t.Errorf("GET /second: orden = %v, se esperaba solo el middleware second", got)
+			}
+		})
+	}
+}
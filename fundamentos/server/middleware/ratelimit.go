@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* Límite de solicitudes (rate limiting) con token bucket
+Cada cliente tiene una "cubeta" con capacidad para `Burst` fichas (tokens):
+
+- Cada solicitud consume una ficha. Si la cubeta está vacía, respondemos 429 (Too Many Requests).
+- La cubeta se rellena de forma continua a razón de `Requests` fichas cada `Per`.
+- Un cliente puede hacer hasta `Burst` solicitudes seguidas (ráfaga), pero a largo plazo no supera
+`Requests` por cada `Per`.
+
+Al cliente se le informa su estado con las cabeceras RateLimit (borrador de la IETF):
+
+- RateLimit-Limit: Capacidad de la cubeta.
+- RateLimit-Remaining: Fichas disponibles después de esta solicitud.
+- RateLimit-Reset: Segundos hasta que la cubeta vuelve a estar llena.
+- Retry-After (solo en 429): Segundos hasta que haya una ficha disponible.
+
+* Memoria acotada:
+Una cubeta que no se usó durante el tiempo que tarda en llenarse está llena, igual que una nueva.
+Por eso podemos eliminarla sin cambiar el comportamiento: en cada solicitud eliminamos las inactivas.
+Las cubetas se guardan en una lista ordenada por su último uso, así solo recorremos las que hay que eliminar.
+
+Eso no alcanza si llegan muchas claves distintas a la vez (por ejemplo, IPs falsificadas o claves de API
+inventadas): todas son recientes. `MaxKeys` limita la cantidad de cubetas y, al superarlo, se elimina
+la que lleva más tiempo sin usarse. Ese cliente vuelve a empezar con la cubeta llena: es preferible
+a que la memoria del servidor crezca sin límite.
+
+* Clave del cliente:
+`Key` decide qué solicitudes comparten una cubeta: por IP (`KeyByIP`), por una cabecera como
+`X-API-Key` (`KeyByHeader`) o cualquier otra función. Cada llamada a RateLimit tiene sus propias
+cubetas, así cada ruta puede tener un límite distinto:
+
+	createLimit := middleware.RateLimit(middleware.Limit{Requests: 10, Per: time.Minute, Burst: 5})
+	mux.Handle("POST /users", createLimit(createUser))
+*/
+
+// KeyFunc obtiene la clave que identifica al cliente de una solicitud.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP usa la IP de la conexión como clave.
+func KeyByIP(r *http.Request) string {
+	return remoteIP(r)
+}
+
+// KeyByHeader usa el valor de la cabecera indicada como clave. Si la solicitud no la trae usa la IP.
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		if value := r.Header.Get(name); value != "" {
+			return name + ":" + value
+		}
+		return KeyByIP(r)
+	}
+}
+
+// Limit configura el límite de solicitudes de una ruta.
+type Limit struct {
+	// Requests es la cantidad de solicitudes permitidas cada `Per`.
+	Requests int
+	Per      time.Duration
+	// Burst es la cantidad máxima de solicitudes seguidas. Si es 0 se usa `Requests`.
+	Burst int
+	// Key obtiene la clave del cliente. Si es nil se usa KeyByIP.
+	Key KeyFunc
+	// MaxKeys es la cantidad máxima de clientes con una cubeta. Si es 0 se usa 10.000.
+	MaxKeys int
+}
+
+// bucket guarda las fichas de un cliente. Las fichas se recalculan al usarla, así no necesitamos
+// una goroutine que las rellene.
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// limiter guarda las cubetas de todos los clientes de un RateLimit.
+type limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// order contiene las cubetas ordenadas por su último uso: la más reciente al frente.
+	order *list.List
+
+	rate     float64 // fichas por segundo
+	burst    float64
+	fillTime time.Duration // tiempo que tarda una cubeta vacía en llenarse
+	maxKeys  int
+}
+
+// RateLimit devuelve un middleware que limita las solicitudes de cada cliente según `limit`.
+func RateLimit(limit Limit) Middleware {
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		panic("middleware: Limit debe tener Requests y Per mayores a 0")
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Requests
+	}
+	if limit.Key == nil {
+		limit.Key = KeyByIP
+	}
+	if limit.MaxKeys <= 0 {
+		limit.MaxKeys = 10_000
+	}
+
+	l := newLimiter(limit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, remaining, retryAfter, reset := l.take(limit.Key(r), time.Now())
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(reset)))
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+				httperror.Write(w, r, httperror.New(http.StatusTooManyRequests,
+					fmt.Sprintf("se superó el límite de solicitudes, intente de nuevo en %d segundos", seconds(retryAfter))))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// newLimiter crea las cubetas de un RateLimit. `limit` ya tiene los valores por defecto.
+func newLimiter(limit Limit) *limiter {
+	rate := float64(limit.Requests) / limit.Per.Seconds()
+	return &limiter{
+		buckets:  make(map[string]*list.Element),
+		order:    list.New(),
+		rate:     rate,
+		burst:    float64(limit.Burst),
+		fillTime: time.Duration(float64(limit.Burst) / rate * float64(time.Second)),
+		maxKeys:  limit.MaxKeys,
+	}
+}
+
+// take intenta consumir una ficha de la cubeta de `key`. Devuelve si la solicitud está permitida,
+// las fichas restantes, el tiempo hasta la próxima ficha (si no está permitida) y el tiempo hasta llenarse.
+func (l *limiter) take(key string, now time.Time) (allowed bool, remaining int, retryAfter, reset time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	var b *bucket
+	if elem, ok := l.buckets[key]; ok {
+		b = elem.Value.(*bucket)
+		l.order.MoveToFront(elem)
+	} else {
+		// Si no hay lugar para otra cubeta, eliminamos la que lleva más tiempo sin usarse.
+		if l.order.Len() >= l.maxKeys {
+			l.remove(l.order.Back())
+		}
+		b = &bucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.order.PushFront(b)
+	}
+
+	// Agregamos las fichas generadas desde la última solicitud, sin superar la capacidad.
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		allowed = true
+	} else {
+		retryAfter = l.duration(1 - b.tokens)
+	}
+	return allowed, int(b.tokens), retryAfter, l.duration(l.burst - b.tokens)
+}
+
+// sweep elimina las cubetas que estuvieron inactivas el tiempo suficiente para llenarse.
+// Recorre la lista desde la menos reciente y se detiene en la primera que sigue activa.
+// Debe llamarse con `mu` bloqueado.
+func (l *limiter) sweep(now time.Time) {
+	for elem := l.order.Back(); elem != nil; elem = l.order.Back() {
+		if now.Sub(elem.Value.(*bucket).last) < l.fillTime {
+			return
+		}
+		l.remove(elem)
+	}
+}
+
+// remove elimina la cubeta del mapa y de la lista. Debe llamarse con `mu` bloqueado.
+func (l *limiter) remove(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.buckets, elem.Value.(*bucket).key)
+}
+
+// duration devuelve el tiempo necesario para generar `tokens` fichas.
+func (l *limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// seconds redondea una duración hacia arriba a segundos enteros, como esperan las cabeceras.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	// 2 solicitudes por minuto: se genera una ficha cada 30 segundos.
+	h := RateLimit(Limit{Requests: 2, Per: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		remoteAddr string
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{"10.0.0.1:1234", http.StatusOK, "1", "30", ""},
+		{"10.0.0.1:1234", http.StatusOK, "0", "60", ""},
+		{"10.0.0.1:1234", http.StatusTooManyRequests, "0", "60", "30"},
+		// Otra IP tiene su propia cubeta.
+		{"10.0.0.2:1234", http.StatusOK, "1", "30", ""},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.RemoteAddr = tt.remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		header := rec.Header()
+		if rec.Code != tt.status {
+			t.Fatalf("solicitud %d: estado %d, se esperaba %d", i+1, rec.Code, tt.status)
+		}
+		if got := header.Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("solicitud %d: RateLimit-Limit = %q, se esperaba 2", i+1, got)
+		}
+		if got := header.Get("RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("solicitud %d: RateLimit-Remaining = %q, se esperaba %q", i+1, got, tt.remaining)
+		}
+		if got := header.Get("RateLimit-Reset"); got != tt.reset {
+			t.Errorf("solicitud %d: RateLimit-Reset = %q, se esperaba %q", i+1, got, tt.reset)
+		}
+		if got := header.Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("solicitud %d: Retry-After = %q, se esperaba %q", i+1, got, tt.retryAfter)
+		}
+		if tt.status == http.StatusTooManyRequests && header.Get("Content-Type") != "application/problem+json" {
+			t.Errorf("solicitud %d: Content-Type = %q, se esperaba application/problem+json", i+1, header.Get("Content-Type"))
+		}
+	}
+}
+
+// keys devuelve las claves de las cubetas del limitador, ordenadas.
+func keys(l *limiter) []string {
+	return slices.Sorted(maps.Keys(l.buckets))
+}
+
+func TestLimiterSweep(t *testing.T) {
+	// Una cubeta de 2 fichas con 1 ficha por segundo tarda 2 segundos en llenarse.
+	l := newLimiter(Limit{Requests: 1, Per: time.Second, Burst: 2, MaxKeys: 10})
+	start := time.Now()
+
+	l.take("a", start)
+	l.take("b", start.Add(time.Second))
+	l.take("c", start.Add(2*time.Second)) // "a" lleva 2 segundos inactiva: ya está llena y se elimina
+
+	if got := keys(l); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("cubetas = %v, se esperaban [b c]", got)
+	}
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	l := newLimiter(Limit{Requests: 1, Per: time.Hour, Burst: 1, MaxKeys: 2})
+	now := time.Now()
+
+	l.take("a", now)
+	l.take("b", now)
+	l.take("a", now) // "a" vuelve a ser la más reciente: "b" es la que lleva más tiempo sin usarse
+	if allowed, _, _, _ := l.take("c", now); !allowed {
+		t.Fatal("la primera solicitud de c debía permitirse")
+	}
+
+	if got := keys(l); !slices.Equal(got, []string{"a", "c"}) {
+		t.Fatalf("cubetas = %v, se esperaban [a c]", got)
+	}
+
+	// La cubeta de "b" se eliminó: vuelve a empezar llena aunque la había vaciado.
+	if allowed, _, _, _ := l.take("b", now); !allowed {
+		t.Error("b debía empezar con una cubeta nueva")
+	}
+	if got := len(l.buckets); got != 2 || l.order.Len() != 2 {
+		t.Errorf("hay %d cubetas en el mapa y %d en la lista, se esperaban 2", got, l.order.Len())
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* Recuperación de pánicos
+net/http ya recupera los pánicos de los manejadores para que el servidor no se detenga, pero solo
+cierra la conexión y escribe la traza en el log estándar: el cliente no recibe ninguna respuesta
+y el registro no se relaciona con la solicitud (ver `recover` en fundamentos/panicrcvr).
+
+`Recover` usa `recover()` dentro de un `defer` para:
+- Registrar el pánico con slog junto a la traza (stack) y el X-Request-ID de la solicitud.
+- Responder un 500 con `application/problem+json` si el manejador todavía no envió las cabeceras.
+Si ya las envió, el código de estado no se puede cambiar: interrumpimos la conexión para que el
+cliente sepa que la respuesta está incompleta.
+- Volver a entrar en pánico con `http.ErrAbortHandler`. Es el valor que un manejador usa a propósito
+para interrumpir la respuesta (ver la exportación de usuarios) y net/http lo maneja sin registrarlo.
+
+`recover()` solo detiene los pánicos de la misma goroutine: si un manejador inicia otra goroutine
+y esta entra en pánico, el programa termina igual.
+*/
+
+// Recover devuelve un middleware que convierte los pánicos de los manejadores en respuestas 500.
+// Debe ir después de AccessLog en la cadena para que el registro incluya el X-Request-ID, y después
+// de los middlewares que agregan cabeceras a todas las respuestas (CORS, SecurityHeaders) para que
+// el 500 también las tenga.
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := NewResponseWriter(w)
+			// Las cabeceras que agregaron los middlewares anteriores (X-Request-ID, CORS, Vary,
+			// Content-Security-Policy, etc.) también valen para la respuesta de error.
+			before := w.Header().Clone()
+
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+
+				// `http.ErrAbortHandler` no es un error del programa: lo dejamos llegar a net/http.
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+
+				logger.LogAttrs(r.Context(), slog.LevelError, "Pánico en el manejador",
+					slog.String("request_id", RequestIDFromContext(r.Context())),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("panic", fmt.Sprint(v)),
+					slog.String("stack", string(debug.Stack())),
+				)
+
+				if wrapped.WroteHeader() {
+					panic(http.ErrAbortHandler)
+				}
+
+				// Descartamos las cabeceras que el manejador alcanzó a preparar (ETag, Location, etc.),
+				// ya que describían una respuesta que no se enviará, y restauramos las de antes de llamarlo.
+				header := wrapped.Header()
+				clear(header)
+				maps.Copy(header, before)
+				httperror.Write(wrapped, r, httperror.New(http.StatusInternalServerError, "ocurrió un error interno en el servidor"))
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
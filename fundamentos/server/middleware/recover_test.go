@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRecoverKeepsOuterHeaders verifica que el 500 conserva las cabeceras de los middlewares
+// anteriores y descarta las que preparó el manejador antes del pánico.
+func TestRecoverKeepsOuterHeaders(t *testing.T) {
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "https://app.example.com")
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Content-Security-Policy", "default-src 'none'")
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		w.Header().Add("Vary", "Accept")
+		panic("falla")
+	})
+
+	h := Chain(outer, Recover(slog.New(slog.DiscardHandler)))(handler)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("estado %d, se esperaba 500", rec.Code)
+	}
+	header := rec.Header()
+	if got := header.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, se esperaba el de CORS", got)
+	}
+	if got := header.Get("Content-Security-Policy"); got == "" {
+		t.Error("falta Content-Security-Policy")
+	}
+	if got := header.Values("Vary"); len(got) != 1 || got[0] != "Origin" {
+		t.Errorf("Vary = %v, se esperaba [Origin]", got)
+	}
+	if got := header.Get("ETag"); got != "" {
+		t.Errorf("ETag = %q, el manejador no terminó y no debía enviarse", got)
+	}
+	if got := header.Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q, se esperaba application/problem+json", got)
+	}
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+/*
+* Envolver http.ResponseWriter
+Para saber qué respondió un manejador (código de estado, tamaño, tiempo) un middleware le pasa su propio
+`http.ResponseWriter` que guarda esos datos y delega en el original. Hay varios detalles a cuidar:
+
+- Estado implícito: Si el manejador llama a `Write` sin llamar antes a `WriteHeader`, net/http envía 200.
+Si solo sobrescribimos `WriteHeader`, ese caso no se registra.
+- Respuestas informativas (1xx): `WriteHeader(103)` (Early Hints) puede llamarse antes de la respuesta
+final y no es el estado definitivo.
+- Interfaces opcionales: El ResponseWriter de net/http también implementa `http.Flusher` (necesario
+para SSE), `http.Hijacker` (necesario para websockets) e `io.ReaderFrom` (permite usar sendfile).
+Un struct que solo embebe `http.ResponseWriter` las oculta: `w.(http.Flusher)` falla.
+- `http.ResponseController` (Go 1.20): Busca el método `Unwrap` para llegar al ResponseWriter original
+y usar funciones como `SetWriteDeadline` aunque el wrapper no las implemente.
+*/
+
+// ResponseWriter envuelve un `http.ResponseWriter` y registra el código de estado, la cantidad de bytes
+// escritos y el tiempo hasta que se enviaron las cabeceras. Conserva `http.Flusher`, `http.Hijacker`
+// e `io.ReaderFrom`, y permite usar `http.ResponseController` gracias a `Unwrap`.
+type ResponseWriter struct {
+	http.ResponseWriter
+
+	start       time.Time
+	status      int
+	bytes       int64
+	firstByte   time.Duration
+	wroteHeader bool
+	hijacked    bool
+}
+
+// NewResponseWriter envuelve `w`. Si `w` ya es un *ResponseWriter lo devuelve sin envolverlo otra vez,
+// así varios middlewares pueden leer los mismos datos.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w, start: time.Now()}
+}
+
+// Status devuelve el código de estado enviado. Si el manejador no escribió nada devuelve 200,
+// que es lo que net/http envía al terminar. Después de Hijack devuelve 101 (Switching Protocols).
+func (w *ResponseWriter) Status() int {
+	switch {
+	case w.hijacked:
+		return http.StatusSwitchingProtocols
+	case !w.wroteHeader:
+		return http.StatusOK
+	default:
+		return w.status
+	}
+}
+
+// BytesWritten devuelve la cantidad de bytes del cuerpo escritos hasta el momento.
+func (w *ResponseWriter) BytesWritten() int64 {
+	return w.bytes
+}
+
+// FirstByte devuelve el tiempo transcurrido hasta que se enviaron las cabeceras (0 si aún no se enviaron).
+func (w *ResponseWriter) FirstByte() time.Duration {
+	return w.firstByte
+}
+
+// WroteHeader indica si ya se enviaron las cabeceras. Después de eso no se puede cambiar el código de estado.
+func (w *ResponseWriter) WroteHeader() bool {
+	return w.wroteHeader || w.hijacked
+}
+
+// Unwrap devuelve el ResponseWriter original. Lo usa `http.ResponseController`.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WriteHeader registra el código de estado de la respuesta final y lo envía.
+func (w *ResponseWriter) WriteHeader(code int) {
+	// Las respuestas 1xx (salvo 101) son informativas: pueden enviarse varias antes de la respuesta final.
+	if !w.wroteHeader && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		w.wroteHeader = true
+		w.status = code
+		w.firstByte = time.Since(w.start)
+	}
+	// Aunque ya se hayan enviado las cabeceras delegamos la llamada: net/http registra la advertencia
+	// "superfluous response.WriteHeader call", útil para encontrar el error.
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// writeHeaderIfNeeded registra el 200 implícito que net/http envía en la primera escritura.
+func (w *ResponseWriter) writeHeaderIfNeeded() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.writeHeaderIfNeeded()
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom permite que `io.Copy(w, file)` siga usando la implementación optimizada del ResponseWriter
+// original (por ejemplo sendfile al servir archivos).
+func (w *ResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.writeHeaderIfNeeded()
+
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		// `writerOnly` oculta nuestro ReadFrom para que `io.Copy` no vuelva a llamarlo.
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, src)
+	}
+	w.bytes += n
+	return n, err
+}
+
+// Flush envía al cliente lo escrito hasta el momento (implementa `http.Flusher`).
+func (w *ResponseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError es igual que Flush, pero devuelve el error. `http.ResponseController` lo usa si existe.
+func (w *ResponseWriter) FlushError() error {
+	w.writeHeaderIfNeeded()
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack toma el control de la conexión (implementa `http.Hijacker`). Lo usan los websockets.
+// Si el ResponseWriter original no lo soporta (HTTP/2) devuelve `http.ErrNotSupported`.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+		w.firstByte = time.Since(w.start)
+	}
+	return conn, rw, err
+}
+
+// writerOnly expone únicamente el método Write de un io.Writer.
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseWriterStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+		want    int
+	}{
+		{name: "sin escribir nada", handler: func(w http.ResponseWriter) {}, want: http.StatusOK},
+		{name: "Write sin WriteHeader", handler: func(w http.ResponseWriter) { io.WriteString(w, "hola") }, want: http.StatusOK},
+		{name: "WriteHeader explícito", handler: func(w http.ResponseWriter) { w.WriteHeader(http.StatusCreated) }, want: http.StatusCreated},
+		{
+			// Early Hints no es la respuesta final.
+			name: "103 antes de la respuesta final",
+			handler: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			// Solo cuenta el primer estado: net/http ignora los siguientes.
+			name: "WriteHeader repetido",
+			handler: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusAccepted)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			want: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Usamos un servidor real: httptest.ResponseRecorder no trata las respuestas 1xx como net/http.
+			status := make(chan int, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rw := NewResponseWriter(w)
+				tt.handler(rw)
+				status <- rw.Status()
+			}))
+			defer srv.Close()
+
+			resp, err := srv.Client().Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got := <-status; got != tt.want {
+				t.Errorf("Status() = %d, se esperaba %d", got, tt.want)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("el cliente recibió %d, se esperaba %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseWriterBytes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec)
+
+	io.WriteString(rw, "hola ")
+	// `io.Copy` usa ReadFrom, que también debe contar los bytes.
+	if _, err := io.Copy(rw, strings.NewReader("mundo")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rw.BytesWritten(); got != int64(len("hola mundo")) {
+		t.Errorf("BytesWritten() = %d, se esperaba %d", got, len("hola mundo"))
+	}
+	if got := rec.Body.String(); got != "hola mundo" {
+		t.Errorf("cuerpo = %q, se esperaba %q", got, "hola mundo")
+	}
+	if !rw.WroteHeader() || rw.Status() != http.StatusOK {
+		t.Errorf("WroteHeader() = %v, Status() = %d; se esperaba el 200 implícito", rw.WroteHeader(), rw.Status())
+	}
+}
+
+func TestNewResponseWriterReuse(t *testing.T) {
+	rw := NewResponseWriter(httptest.NewRecorder())
+	if NewResponseWriter(rw) != rw {
+		t.Error("NewResponseWriter volvió a envolver un *ResponseWriter")
+	}
+}
+
+func TestResponseWriterFlush(t *testing.T) {
+	t.Run("http.Flusher", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		var w http.ResponseWriter = NewResponseWriter(rec)
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("el ResponseWriter no implementa http.Flusher")
+		}
+		flusher.Flush()
+		if !rec.Flushed {
+			t.Error("Flush no llegó al ResponseWriter original")
+		}
+	})
+
+	t.Run("http.ResponseController", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := NewResponseWriter(rec)
+
+		if err := http.NewResponseController(rw).Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		if !rec.Flushed {
+			t.Error("Flush no llegó al ResponseWriter original")
+		}
+		// Flush envía las cabeceras: el cliente ya recibió el 200.
+		if !rw.WroteHeader() || rw.Status() != http.StatusOK {
+			t.Errorf("WroteHeader() = %v, Status() = %d; se esperaba el 200 implícito", rw.WroteHeader(), rw.Status())
+		}
+	})
+}
+
+// TestResponseWriterController verifica que `http.ResponseController` llega al ResponseWriter de net/http
+// a través de Unwrap, aunque nuestro wrapper no implemente SetWriteDeadline.
+func TestResponseWriterController(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(NewResponseWriter(w))
+		if err := rc.SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("SetWriteDeadline: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	t.Run("HTTP/1.1", func(t *testing.T) {
+		status := make(chan int, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := NewResponseWriter(w)
+			conn, buf, err := rw.Hijack()
+			if err != nil {
+				t.Errorf("Hijack: %v", err)
+				status <- 0
+				return
+			}
+			defer conn.Close()
+
+			// Desde aquí la conexión es nuestra: escribimos la respuesta a mano.
+			buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: prueba\r\n\r\n")
+			buf.Flush()
+			status <- rw.Status()
+		}))
+		defer srv.Close()
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "prueba")
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Errorf("el cliente recibió %d, se esperaba 101", resp.StatusCode)
+		}
+		if got := <-status; got != http.StatusSwitchingProtocols {
+			t.Errorf("Status() = %d, se esperaba 101", got)
+		}
+	})
+
+	t.Run("sin soporte", func(t *testing.T) {
+		// httptest.ResponseRecorder no implementa http.Hijacker, como el ResponseWriter de HTTP/2.
+		rw := NewResponseWriter(httptest.NewRecorder())
+		var w http.ResponseWriter = rw
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Fatal("el ResponseWriter no implementa http.Hijacker")
+		}
+
+		_, _, err := rw.Hijack()
+		if !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Hijack: error %v, se esperaba http.ErrNotSupported", err)
+		}
+		if rw.WroteHeader() {
+			t.Error("un Hijack fallido no debe marcar las cabeceras como enviadas")
+		}
+	})
+}
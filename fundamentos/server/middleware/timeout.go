@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* Tiempo límite por ruta
+Los timeouts de `http.Server` (ReadTimeout, WriteTimeout) cierran la conexión, pero el manejador sigue
+trabajando y el cliente no recibe ninguna respuesta. `Timeout` agrega un plazo (deadline) al contexto
+de la solicitud y, si el manejador no termina a tiempo, responde 503 con `application/problem+json`.
+
+Funciona como `http.TimeoutHandler`:
+- El manejador se ejecuta en otra goroutine y escribe en un búfer, no directamente en la conexión.
+- Si termina antes del plazo, copiamos su respuesta (cabeceras, estado y cuerpo) al cliente.
+- Si se vence el plazo, respondemos 503 y descartamos lo que escriba después (`http.ErrHandlerTimeout`).
+- Si el manejador entra en pánico, el pánico se repite en la goroutine de la solicitud para que
+Recover pueda responder un 500.
+
+El contexto con el plazo llega al almacenamiento y a la base de datos (`QueryContext`, pgx, etc.): cuando
+se vence, esas llamadas terminan con `context.DeadlineExceeded` y la goroutine del manejador deja de
+trabajar. Un manejador que ignora `r.Context()` sigue ejecutándose aunque el cliente ya recibió el 503.
+
+Como la respuesta se guarda completa en memoria, Timeout no sirve para respuestas por partes
+(Server-Sent Events, exportaciones): no implementa `http.Flusher`. Cada ruta puede tener un plazo distinto:
+
+	group.Handle("GET /users/{id}", getUser, middleware.Timeout(2*time.Second))
+*/
+
+// Timeout devuelve un middleware que cancela el contexto de la solicitud después de `d`
+// y responde 503 si el manejador no terminó para entonces.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			// Con capacidad 1 la goroutine puede terminar aunque ya hayamos respondido el 503.
+			panicked := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if v := recover(); v != nil {
+						panicked <- v
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case v := <-panicked:
+				panic(v)
+
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				maps.Copy(w.Header(), tw.header)
+				// Si el manejador no escribió nada, net/http responde 200 sin cuerpo.
+				if tw.status != 0 {
+					w.WriteHeader(tw.status)
+				}
+				if tw.body.Len() > 0 {
+					w.Write(tw.body.Bytes())
+				}
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+
+				// Si el contexto se canceló porque el cliente se desconectó, no hay a quién responder.
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					httperror.Write(w, r, httperror.New(http.StatusServiceUnavailable,
+						fmt.Sprintf("la solicitud superó el tiempo límite de %s", d)))
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter guarda la respuesta del manejador hasta que Timeout decide si la envía.
+type timeoutWriter struct {
+	header http.Header
+
+	mu       sync.Mutex
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	// Igual que net/http, ignoramos las llamadas repetidas. Las respuestas 1xx no se pueden
+	// guardar para después, así que también se ignoran.
+	if tw.timedOut || tw.status != 0 || code < 200 {
+		return
+	}
+	tw.status = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(b)
+}
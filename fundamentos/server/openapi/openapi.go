@@ -0,0 +1,300 @@
+// Package openapi genera un documento OpenAPI 3.1 a partir de las rutas registradas en un `http.ServeMux`.
+package openapi
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+	"github.com/Mayer-04/logica-go/fundamentos/server/middleware"
+)
+
+/*
+* OpenAPI
+OpenAPI es un formato estándar (JSON o YAML) para describir una API HTTP: sus rutas, parámetros,
+cuerpos de solicitud y respuestas. Con ese documento se pueden generar clientes, documentación
+interactiva (Swagger UI, Redoc) o validar solicitudes.
+
+Escribir el documento a mano tiene un problema: se desactualiza cuando cambia el código.
+En lugar de eso lo generamos a partir de lo que el servidor ya conoce:
+
+- Los patrones de Go 1.22 como "GET /users/{id}" contienen el método, la ruta y los parámetros de ruta.
+- Los tipos de Go de las solicitudes y respuestas (con sus etiquetas `json` y `validate`) se convierten
+en esquemas JSON Schema usando el paquete `reflect`.
+
+El `Router` reemplaza a `mux.Handle`: exige la documentación de cada ruta al registrarla.
+Si se registra un manejador sin documentación, el servidor entra en pánico al iniciar,
+así es imposible agregar una ruta sin que aparezca en el documento.
+
+	router := openapi.NewRouter(mux)
+	router.Handle("GET /users/{id}", handler, openapi.Operation{
+		Summary:  "Obtiene un usuario",
+		Response: User{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	router.Handle("GET /openapi.json", router.Spec(openapi.Info{Title: "Users API", Version: "1.0.0"}), ...)
+
+Con `Group` se crean grupos de rutas que comparten middlewares (ver paquete middleware).
+Las rutas de todos los grupos aparecen en el mismo documento:
+
+	admin := router.Group(requireAdmin)
+	admin.Handle("DELETE /users/{id}", deleteUser, openapi.Operation{Summary: "Elimina un usuario"})
+*/
+
+// Operation documenta una ruta al registrarla en el Router.
+type Operation struct {
+	// Summary es una descripción corta de la operación. Es obligatoria.
+	Summary string
+	// Request es un valor del tipo del cuerpo de la solicitud, por ejemplo `User{}`. Nil si no tiene cuerpo.
+	Request any
+	// RequestContentType es el tipo de contenido del cuerpo. Por defecto `application/json`.
+	RequestContentType string
+	// Response es un valor del tipo del cuerpo de la respuesta exitosa. Nil si no tiene cuerpo.
+	Response any
+	// ResponseContentType es el tipo de contenido de la respuesta. Por defecto `application/json`.
+	ResponseContentType string
+	// Status es el código de la respuesta exitosa. Por defecto 200.
+	Status int
+	// Parameters son los parámetros de consulta y cabeceras. Los parámetros de ruta se obtienen del patrón.
+	Parameters []Parameter
+	// Errors son los códigos de error que puede devolver, documentados como `application/problem+json`.
+	Errors []int
+}
+
+// Query crea un parámetro de consulta (?name=value) de tipo string.
+func Query(name, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+// QueryInt crea un parámetro de consulta de tipo entero.
+func QueryInt(name, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "integer"}}
+}
+
+// Header crea un parámetro de cabecera de tipo string.
+func Header(name, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+// route es una ruta registrada junto con su documentación.
+type route struct {
+	pattern string
+	method  string
+	path    string
+	op      Operation
+}
+
+// Router registra rutas en un `http.ServeMux` y guarda su documentación.
+// También es un `http.Handler`: atiende las solicitudes con el multiplexor.
+type Router struct {
+	mux   *http.ServeMux
+	group *middleware.Group
+	// routes se comparte entre el Router y sus grupos, así el documento incluye las rutas de todos.
+	routes *[]route
+}
+
+// NewRouter crea un Router que registra las rutas en `mux`.
+func NewRouter(mux *http.ServeMux) *Router {
+	return &Router{mux: mux, group: middleware.NewGroup(mux), routes: new([]route)}
+}
+
+// Group crea un Router que aplica los middlewares indicados (después de los de este Router)
+// a las rutas que registra. Ambos comparten el multiplexor y el documento.
+func (rt *Router) Group(middlewares ...middleware.Middleware) *Router {
+	return &Router{mux: rt.mux, group: rt.group.Group(middlewares...), routes: rt.routes}
+}
+
+// ServeHTTP atiende la solicitud con el multiplexor.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+// Handler devuelve el manejador y el patrón que el multiplexor elige para la solicitud (ver `http.ServeMux.Handler`).
+func (rt *Router) Handler(r *http.Request) (http.Handler, string) {
+	return rt.mux.Handler(r)
+}
+
+// Routes devuelve los patrones registrados con el Router y sus grupos, en el orden en que se registraron.
+func (rt *Router) Routes() []string {
+	patterns := make([]string, 0, len(*rt.routes))
+	for _, r := range *rt.routes {
+		patterns = append(patterns, r.pattern)
+	}
+	return patterns
+}
+
+// Handle registra `handler` en el multiplexor con el patrón indicado y guarda su documentación.
+// El patrón debe incluir el método ("GET /users") y la operación debe tener un resumen.
+func (rt *Router) Handle(pattern string, handler http.Handler, op Operation) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok || op.Summary == "" {
+		panic(fmt.Sprintf("openapi: la ruta %q debe incluir el método y estar documentada con un Summary", pattern))
+	}
+
+	rt.group.Handle(pattern, handler)
+
+	// En el documento, "/{$}" se escribe como "/" y los comodines "{path...}" como "{path}".
+	path = strings.NewReplacer("{$}", "", "...}", "}").Replace(strings.TrimSpace(path))
+	*rt.routes = append(*rt.routes, route{pattern: pattern, method: strings.ToLower(method), path: path, op: op})
+}
+
+// HandleFunc es igual que Handle, pero recibe una función.
+func (rt *Router) HandleFunc(pattern string, handler http.HandlerFunc, op Operation) {
+	rt.Handle(pattern, handler, op)
+}
+
+// Document genera el documento OpenAPI con todas las rutas registradas hasta el momento.
+func (rt *Router) Document(info Info) Document {
+	builder := newSchemaBuilder()
+	doc := Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+
+	for _, r := range *rt.routes {
+		item, ok := doc.Paths[r.path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[r.path] = item
+		}
+		item[r.method] = builder.operation(r)
+	}
+
+	doc.Components.Schemas = builder.schemas
+	return doc
+}
+
+// Spec devuelve un manejador que responde con el documento OpenAPI en formato JSON.
+// El documento se genera en cada solicitud, así incluye las rutas registradas después de llamar a Spec.
+func (rt *Router) Spec(info Info) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.MarshalIndent(rt.Document(info), "", "  ")
+		if err != nil {
+			httperror.Write(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(body, '\n'))
+	})
+}
+
+//* Tipos del documento OpenAPI 3.1.
+
+// Document es la raíz de un documento OpenAPI.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describe la API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem agrupa las operaciones de una ruta por método ("get", "post", etc.).
+type PathItem map[string]*OperationObject
+
+// OperationObject es una operación en el documento generado.
+type OperationObject struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter es un parámetro de ruta, consulta o cabecera.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describe el cuerpo de la solicitud.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describe una respuesta.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType asocia un tipo de contenido con su esquema.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components contiene los esquemas reutilizables referenciados con `$ref`.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// operation convierte una ruta registrada en un OperationObject.
+func (b *schemaBuilder) operation(r route) *OperationObject {
+	op := &OperationObject{
+		OperationID: operationID(r.method, r.path),
+		Summary:     r.op.Summary,
+		Responses:   make(map[string]Response),
+	}
+
+	// Los parámetros de ruta se obtienen del patrón: "/users/{id}" → id.
+	for segment := range strings.SplitSeq(r.path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			name = strings.TrimSuffix(name, "}")
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+	op.Parameters = append(op.Parameters, r.op.Parameters...)
+
+	if r.op.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				cmp.Or(r.op.RequestContentType, "application/json"): {Schema: b.schemaFor(reflect.TypeOf(r.op.Request))},
+			},
+		}
+	}
+
+	status := cmp.Or(r.op.Status, http.StatusOK)
+	response := Response{Description: http.StatusText(status)}
+	if r.op.Response != nil {
+		response.Content = map[string]MediaType{
+			cmp.Or(r.op.ResponseContentType, "application/json"): {Schema: b.schemaFor(reflect.TypeOf(r.op.Response))},
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+
+	// Todas las respuestas de error comparten el esquema de `httperror.Problem`.
+	for _, code := range r.op.Errors {
+		op.Responses[strconv.Itoa(code)] = Response{
+			Description: http.StatusText(code),
+			Content: map[string]MediaType{
+				httperror.ContentType: {Schema: b.schemaFor(reflect.TypeFor[httperror.Problem]())},
+			},
+		}
+	}
+	return op
+}
+
+// operationID genera un identificador único de la operación, por ejemplo "get_users_id".
+func operationID(method, path string) string {
+	replacer := strings.NewReplacer("/", "_", "{", "", "}", "", ".", "", ":", "_")
+	return strings.Trim(method+replacer.Replace(path), "_")
+}
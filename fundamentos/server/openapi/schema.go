@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+* Esquemas a partir de tipos de Go
+Usamos `reflect` para recorrer los tipos y generar su JSON Schema:
+
+- string → {"type": "string"}, int → {"type": "integer"}, bool → {"type": "boolean"}, etc.
+- slices → {"type": "array", "items": ...}
+- estructuras con nombre → se guardan en `components.schemas` y se referencian con `$ref`.
+- Las etiquetas `json` definen el nombre de cada propiedad (los campos con "-" se omiten).
+- Las etiquetas `validate` agregan restricciones: required, email (format), min y max.
+*/
+
+// Schema es un esquema JSON Schema (OpenAPI 3.1 usa JSON Schema 2020-12).
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+}
+
+// schemaBuilder genera esquemas y acumula los de las estructuras con nombre.
+type schemaBuilder struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{schemas: make(map[string]*Schema)}
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schemaFor devuelve el esquema del tipo `t`.
+func (b *schemaBuilder) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		// Cualquier valor JSON.
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		// Guardamos el esquema antes de construirlo para que los tipos recursivos no generen un ciclo infinito.
+		if _, ok := b.schemas[t.Name()]; !ok {
+			b.schemas[t.Name()] = &Schema{}
+			*b.schemas[t.Name()] = *b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema genera el esquema de una estructura a partir de sus campos exportados.
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for field := range fieldsOf(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		// Solo los campos con la regla `required` son obligatorios. No usamos `omitempty` para decidirlo
+		// porque el mismo esquema describe solicitudes y respuestas (por ejemplo, el cliente no envía el id).
+		property := b.schemaFor(field.Type)
+		if applyRules(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// fieldsOf recorre los campos exportados de una estructura, incluyendo los de estructuras embebidas.
+func fieldsOf(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				for embedded := range fieldsOf(field.Type) {
+					if !yield(embedded) {
+						return
+					}
+				}
+				continue
+			}
+			if field.IsExported() && !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// applyRules agrega al esquema las restricciones de la etiqueta `validate`
+// y devuelve true si el campo es obligatorio.
+func applyRules(schema *Schema, tag string) bool {
+	required := false
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		n, _ := strconv.Atoi(param)
+
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "min":
+			if schema.Type == "string" {
+				schema.MinLength = &n
+			} else {
+				schema.Minimum = &n
+			}
+		case "max":
+			if schema.Type == "string" {
+				schema.MaxLength = &n
+			} else {
+				schema.Maximum = &n
+			}
+		}
+	}
+	return required
+}
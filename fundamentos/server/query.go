@@ -0,0 +1,156 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+/*
+* Filtrado, ordenamiento y búsqueda de usuarios
+`GET /users` acepta los siguientes parámetros de consulta (query parameters):
+
+- name: Usuarios cuyo nombre coincide exactamente, sin distinguir mayúsculas (?name=mayer).
+- email_domain: Usuarios cuyo email pertenece al dominio indicado (?email_domain=example.com).
+- q: Búsqueda de una subcadena en el nombre o el email, sin distinguir mayúsculas (?q=may).
+- sort: Campos de ordenamiento separados por comas. Un `-` delante indica orden descendente
+(?sort=name,-id). Por defecto se ordena por id ascendente.
+- limit y offset: Paginación, se aplica después de filtrar y ordenar.
+
+Todos los almacenamientos usan `applyUserQuery`, así el resultado es el mismo sin importar dónde
+se guarden los usuarios. La excepción son las consultas sin filtros ordenadas por id (ver byIDOnly):
+el almacenamiento en memoria mantiene los IDs ordenados y las responde sin copiar ni ordenar a todos.
+*/
+
+// UserQuery describe qué usuarios devolver en `GET /users` y en qué orden.
+type UserQuery struct {
+	Name        string
+	EmailDomain string
+	Search      string
+	Sort        []SortField
+	// AfterID omite los usuarios con un ID menor o igual (paginación por clave, ver bulk.go).
+	AfterID int
+	Offset  int
+	Limit   int
+}
+
+// SortField es un campo de ordenamiento y su dirección.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// userComparators contiene la función de comparación de cada campo por el que se puede ordenar.
+var userComparators = map[string]func(a, b User) int{
+	"id":    func(a, b User) int { return cmp.Compare(a.ID, b.ID) },
+	"name":  func(a, b User) int { return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) },
+	"email": func(a, b User) int { return cmp.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email)) },
+}
+
+// parseSort convierte un valor como "name,-id" en una lista de SortField.
+func parseSort(value string) ([]SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	for part := range strings.SplitSeq(value, ",") {
+		field := SortField{Field: strings.TrimSpace(part)}
+		if rest, ok := strings.CutPrefix(field.Field, "-"); ok {
+			field = SortField{Field: rest, Desc: true}
+		}
+		if _, ok := userComparators[field.Field]; !ok {
+			return nil, fmt.Errorf("no se puede ordenar por %q", field.Field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// newUserQuery lee los filtros y el ordenamiento de los parámetros de la URL.
+// La paginación (limit y offset) se valida aparte en el manejador.
+func newUserQuery(values url.Values) (UserQuery, error) {
+	sort, err := parseSort(values.Get("sort"))
+	if err != nil {
+		return UserQuery{}, err
+	}
+
+	return UserQuery{
+		Name:        values.Get("name"),
+		EmailDomain: values.Get("email_domain"),
+		Search:      values.Get("q"),
+		Sort:        sort,
+	}, nil
+}
+
+// matches indica si el usuario cumple todos los filtros de la consulta.
+func (q UserQuery) matches(user User) bool {
+	if user.ID <= q.AfterID {
+		return false
+	}
+
+	if q.Name != "" && !strings.EqualFold(user.Name, q.Name) {
+		return false
+	}
+
+	if q.EmailDomain != "" {
+		_, domain, _ := strings.Cut(user.Email, "@")
+		if !strings.EqualFold(domain, q.EmailDomain) {
+			return false
+		}
+	}
+
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(user.Name), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// byIDOnly indica si la consulta no tiene filtros y ordena solo por ID ascendente. Así la piden los lotes
+// de la exportación y `GET /users` sin parámetros: un almacenamiento puede responderla sin ordenar los usuarios.
+func (q UserQuery) byIDOnly() bool {
+	if q.Name != "" || q.EmailDomain != "" || q.Search != "" {
+		return false
+	}
+	return len(q.Sort) == 0 || (len(q.Sort) == 1 && q.Sort[0] == SortField{Field: "id"})
+}
+
+// compare compara dos usuarios según los campos de ordenamiento.
+// El id se usa siempre como último criterio para que el orden sea estable entre solicitudes.
+func (q UserQuery) compare(a, b User) int {
+	for _, field := range q.Sort {
+		result := userComparators[field.Field](a, b)
+		if field.Desc {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// applyUserQuery filtra, ordena y pagina `users`. Devuelve la página y el total de usuarios que
+// cumplen los filtros (antes de paginar).
+func applyUserQuery(users []User, q UserQuery) ([]User, int) {
+	filtered := slices.DeleteFunc(users, func(user User) bool {
+		return !q.matches(user)
+	})
+	slices.SortFunc(filtered, q.compare)
+
+	// Recortamos los usuarios a la página solicitada sin salirnos de los límites del slice.
+	total := len(filtered)
+	start := min(q.Offset, total)
+	end := min(start+q.Limit, total)
+
+	// Devolvemos un slice vacío (y no nil) para que el JSON sea `[]` en lugar de `null`.
+	page := make([]User, 0, end-start)
+	return append(page, filtered[start:end]...), total
+}
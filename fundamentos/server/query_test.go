@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+// queryTestUsers son los usuarios sobre los que se ejecutan las consultas. Se crean en este orden,
+// así el ID de cada uno es su posición más uno.
+var queryTestUsers = []User{
+	{Name: "Mayer", Email: "mayer@example.com"},   // 1
+	{Name: "ana", Email: "ana@test.org"},          // 2
+	{Name: "Carlos", Email: "carlos@example.com"}, // 3
+	{Name: "Ana", Email: "ana.maria@example.com"}, // 4
+	{Name: "beto", Email: "beto@EXAMPLE.com"},     // 5
+}
+
+// TestListUsersQuery ejecuta la misma tabla de consultas sobre cada almacenamiento: ambos deben
+// devolver exactamente los mismos usuarios, en el mismo orden.
+func TestListUsersQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		ids    []int  // IDs esperados, en orden
+		total  int    // total esperado antes de paginar
+		field  string // campo con error si status es 400
+	}{
+		{name: "sin filtros", query: "", status: http.StatusOK, ids: []int{1, 2, 3, 4, 5}, total: 5},
+		{name: "name sin distinguir mayúsculas", query: "name=ANA", status: http.StatusOK, ids: []int{2, 4}, total: 2},
+		{name: "name exacto", query: "name=an", status: http.StatusOK, ids: []int{}, total: 0},
+		{name: "email_domain", query: "email_domain=example.com", status: http.StatusOK, ids: []int{1, 3, 4, 5}, total: 4},
+		{name: "q en nombre o email", query: "q=MAR", status: http.StatusOK, ids: []int{4}, total: 1},
+		{name: "q combinado con email_domain", query: "q=a&email_domain=test.org", status: http.StatusOK, ids: []int{2}, total: 1},
+		{name: "sort=name,-id", query: "sort=name,-id", status: http.StatusOK, ids: []int{4, 2, 5, 3, 1}, total: 5},
+		{name: "sort=-email", query: "sort=-email", status: http.StatusOK, ids: []int{1, 3, 5, 2, 4}, total: 5},
+		{name: "sort con campo inválido", query: "sort=name,password", status: http.StatusBadRequest, field: "sort"},
+		{name: "sort con campo vacío", query: "sort=name,", status: http.StatusBadRequest, field: "sort"},
+		{name: "limit y offset", query: "sort=-id&limit=2&offset=1", status: http.StatusOK, ids: []int{4, 3}, total: 5},
+		{name: "offset en el último usuario", query: "limit=2&offset=4", status: http.StatusOK, ids: []int{5}, total: 5},
+		{name: "offset mayor que el total", query: "offset=10", status: http.StatusOK, ids: []int{}, total: 5},
+		{name: "limit máximo", query: "limit=100", status: http.StatusOK, ids: []int{1, 2, 3, 4, 5}, total: 5},
+		{name: "limit 0", query: "limit=0", status: http.StatusBadRequest, field: "limit"},
+		{name: "limit mayor que el máximo", query: "limit=101", status: http.StatusBadRequest, field: "limit"},
+		{name: "limit no numérico", query: "limit=diez", status: http.StatusBadRequest, field: "limit"},
+		{name: "offset negativo", query: "offset=-1", status: http.StatusBadRequest, field: "offset"},
+	}
+
+	for storeName, store := range testStores(t) {
+		for _, user := range queryTestUsers {
+			if _, err := store.Create(t.Context(), user); err != nil {
+				t.Fatalf("%s: Create: %v", storeName, err)
+			}
+		}
+		handler := newTestHandler(t, store)
+
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?"+tt.query, nil))
+
+				if rec.Code != tt.status {
+					t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+				}
+
+				if tt.status != http.StatusOK {
+					var problem httperror.Problem
+					if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+						t.Fatalf("cuerpo inválido: %v", err)
+					}
+					if len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field {
+						t.Errorf("errors = %+v, se esperaba un error en %q", problem.Errors, tt.field)
+					}
+					return
+				}
+
+				var page UserPage
+				if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+					t.Fatalf("cuerpo inválido: %v", err)
+				}
+				ids := make([]int, 0, len(page.Users))
+				for _, user := range page.Users {
+					ids = append(ids, user.ID)
+				}
+				if !slices.Equal(ids, tt.ids) {
+					t.Errorf("ids = %v, se esperaba %v", ids, tt.ids)
+				}
+				if page.Total != tt.total {
+					t.Errorf("total = %d, se esperaba %d", page.Total, tt.total)
+				}
+			})
+		}
+	}
+}
+
+// TestListAfterID verifica la paginación por clave (`AfterID`) con usuarios eliminados en medio:
+// el resultado debe ser el mismo que filtrar y ordenar todos los usuarios.
+func TestListAfterID(t *testing.T) {
+	ctx := t.Context()
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i := range 10 {
+				if _, err := store.Create(ctx, User{Name: "Usuario", Email: fmt.Sprintf("u%d@example.com", i)}); err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+			}
+			for _, id := range []int{1, 4, 5, 10} {
+				if err := store.Delete(ctx, id, 0); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+			}
+
+			tests := []struct {
+				query UserQuery
+				ids   []int
+				total int
+			}{
+				{UserQuery{Limit: 3}, []int{2, 3, 6}, 6},
+				{UserQuery{AfterID: 3, Limit: 3}, []int{6, 7, 8}, 4},
+				{UserQuery{AfterID: 4, Limit: 2, Offset: 1}, []int{7, 8}, 4},
+				{UserQuery{AfterID: 8, Limit: 3}, []int{9}, 1},
+				{UserQuery{AfterID: 9, Limit: 3}, []int{}, 0},
+				{UserQuery{AfterID: 3, Limit: 2, Sort: []SortField{{Field: "id"}}}, []int{6, 7}, 4},
+			}
+			for _, tt := range tests {
+				users, total, err := store.List(ctx, tt.query)
+				if err != nil {
+					t.Fatalf("List(%+v): %v", tt.query, err)
+				}
+				ids := make([]int, 0, len(users))
+				for _, user := range users {
+					ids = append(ids, user.ID)
+				}
+				if !slices.Equal(ids, tt.ids) || total != tt.total {
+					t.Errorf("List(%+v) = %v, total %d; se esperaba %v, total %d", tt.query, ids, total, tt.ids, tt.total)
+				}
+			}
+		})
+	}
+}
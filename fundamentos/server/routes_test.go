@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Mayer-04/logica-go/fundamentos/server/openapi"
+)
+
+// openAPIDocument obtiene el documento que sirve `GET /openapi.json`.
+func openAPIDocument(t *testing.T, handler http.Handler) openapi.Document {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json: estado %d", rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("documento inválido: %v", err)
+	}
+	return doc
+}
+
+// TestRoutesDocumented verifica que cada ruta registrada en el router es la que elige el multiplexor
+// y aparece en el documento OpenAPI con el mismo método, y que el documento no tiene operaciones de más.
+// `routes` solo puede registrar rutas a través del router, así que sus patrones son todas las rutas.
+func TestRoutesDocumented(t *testing.T) {
+	router := newApp(NewMemoryStore(), nil).routes()
+	doc := openAPIDocument(t, router)
+
+	wildcard := regexp.MustCompile(`\{[^}]*\}`)
+	patterns := router.Routes()
+	if len(patterns) == 0 {
+		t.Fatal("routes() no registró ninguna ruta")
+	}
+	for _, pattern := range patterns {
+		method, path, _ := strings.Cut(pattern, " ")
+
+		// Enviamos al multiplexor una solicitud que coincide con el patrón (`{id}` → "1") y comprobamos
+		// que la atiende esa misma ruta.
+		target := strings.ReplaceAll(path, "{$}", "")
+		target = wildcard.ReplaceAllString(target, "1")
+		if _, matched := router.Handler(httptest.NewRequest(method, target, nil)); matched != pattern {
+			t.Errorf("%s %s: el multiplexor eligió %q, se esperaba %q", method, target, matched, pattern)
+		}
+
+		docPath := strings.NewReplacer("{$}", "", "...}", "}").Replace(path)
+		if doc.Paths[docPath][strings.ToLower(method)] == nil {
+			t.Errorf("%q no aparece en el documento OpenAPI como paths[%q][%q]", pattern, docPath, strings.ToLower(method))
+		}
+	}
+
+	operations := 0
+	for _, item := range doc.Paths {
+		operations += len(item)
+	}
+	if operations != len(patterns) {
+		t.Errorf("el documento tiene %d operaciones y routes() registra %d rutas", operations, len(patterns))
+	}
+}
+
+// TestPatchSchemaHasNoRequiredFields verifica que el cuerpo de PATCH no exige campos: un parche
+// de JSON Merge Patch solo incluye los que cambian.
+func TestPatchSchemaHasNoRequiredFields(t *testing.T) {
+	doc := openAPIDocument(t, newApp(NewMemoryStore(), nil).routes())
+
+	body := doc.Paths["/users/{id}"]["patch"].RequestBody
+	if body == nil {
+		t.Fatal("PATCH /users/{id} no documenta el cuerpo")
+	}
+	media, ok := body.Content["application/merge-patch+json"]
+	if !ok {
+		t.Fatalf("content = %v, se esperaba application/merge-patch+json", body.Content)
+	}
+
+	name := strings.TrimPrefix(media.Schema.Ref, "#/components/schemas/")
+	schema := doc.Components.Schemas[name]
+	if schema == nil {
+		t.Fatalf("no existe el esquema %q", media.Schema.Ref)
+	}
+	if len(schema.Required) != 0 {
+		t.Errorf("required = %v, se esperaba ninguno", schema.Required)
+	}
+	for _, field := range []string{"name", "email"} {
+		if schema.Properties[field] == nil {
+			t.Errorf("el esquema no tiene la propiedad %q", field)
+		}
+	}
+}
+
+// TestHandlerInvalidCORSOrigin verifica que un origen mal escrito en -cors-origins impide iniciar el servidor.
+func TestHandlerInvalidCORSOrigin(t *testing.T) {
+	a := newApp(NewMemoryStore(), nil)
+	if _, err := a.handler(config{corsOrigins: []string{"https://app.example.com/"}}); err == nil {
+		t.Error("se esperaba un error con un origen con barra final")
+	}
+	if _, err := a.handler(config{corsOrigins: []string{"*", "https://app.example.com"}}); err != nil {
+		t.Errorf("handler: %v", err)
+	}
+}
+
+// TestNewAuthenticatorFailsClosed verifica que sin credenciales configuradas el servidor no inicia,
+// salvo que se pida de forma explícita con -insecure-no-auth.
+func TestNewAuthenticatorFailsClosed(t *testing.T) {
+	if _, err := newAuthenticator(config{}); err == nil {
+		t.Error("se esperaba un error sin claves de API ni JWT_SECRET")
+	}
+	if authn, err := newAuthenticator(config{insecureNoAuth: true}); err != nil || authn != nil {
+		t.Errorf("con -insecure-no-auth: authn = %v, err = %v; se esperaba nil, nil", authn, err)
+	}
+	if authn, err := newAuthenticator(config{jwtSecret: "secreto"}); err != nil || authn == nil {
+		t.Errorf("con JWT_SECRET: authn = %v, err = %v; se esperaba un autenticador", authn, err)
+	}
+}
@@ -1,14 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
-	"sync"
+	"strings"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+	"github.com/Mayer-04/logica-go/fundamentos/server/auth"
+	"github.com/Mayer-04/logica-go/fundamentos/server/health"
+	"github.com/Mayer-04/logica-go/fundamentos/server/metrics"
+	"github.com/Mayer-04/logica-go/fundamentos/server/middleware"
+	"github.com/Mayer-04/logica-go/fundamentos/server/openapi"
+	"github.com/Mayer-04/logica-go/fundamentos/server/validate"
 )
 
 /*
@@ -55,49 +70,424 @@ que puede aceptar datos, como un archivo, la salida estándar o una respuesta HT
 
 // Definimos una estructura 'User' para representar un usuario.
 // Las etiquetas `json:""` especifican cómo los campos serán representados en JSON.
+// Las etiquetas `validate:""` indican las reglas que deben cumplir los datos recibidos (ver paquete `validate`).
+// `Version` aumenta en cada modificación y se usa para generar la cabecera `ETag` (ver etag.go).
 type User struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Name    string `json:"name" validate:"required,max=100"`
+	Email   string `json:"email" validate:"required,email,max=254"`
 }
 
-// * Simulación de una base de datos en memoria.
-// La variable 'users' almacena los usuarios en un mapa.
-// La clave es un entero que representa el ID del usuario, y el valor es un estructura `User`.
-var (
-	users = make(map[int]User)
-	mu    sync.RWMutex
-)
+// UserPatch documenta el cuerpo de `PATCH /users/{id}` en el documento OpenAPI. Tiene las mismas
+// reglas que User, pero ningún campo es obligatorio: un parche solo incluye los campos que cambian.
+// El manejador no lo usa: aplica el parche sobre el JSON del usuario actual (ver mergePatch).
+type UserPatch struct {
+	Name  string `json:"name,omitempty" validate:"max=100"`
+	Email string `json:"email,omitempty" validate:"email,max=254"`
+}
+
+// app agrupa las dependencias que comparten los manejadores de la API de usuarios.
+// Los manejadores son métodos de `app`, así pueden usar el almacenamiento sin variables globales.
+type app struct {
+	store UserStore
+	// authn identifica al cliente en las rutas que modifican usuarios (ver paquete auth).
+	// Es nil solo si el servidor se inició con -insecure-no-auth: la autenticación está desactivada.
+	authn auth.Authenticator
+	// events reparte los cambios de usuarios a los clientes de `/users/events` (ver events.go).
+	events *eventBroker
+	// health expone `/healthz`, `/readyz` y `/version`, y guarda si el servidor acepta tráfico.
+	health *health.Health
+	// metrics guarda las métricas HTTP y del runtime que se exponen en `/metrics` (ver paquete metrics).
+	metrics *metrics.Registry
+}
 
 func main() {
-	// Crea un servidor HTTP.
+	// Elegimos la configuración del servidor al iniciarlo mediante banderas (flags).
+	// Ejemplo: go run ./fundamentos/server -store=file -data=users.jsonl -addr=:9090 -api-keys=keys.json
+	// Para probarlo sin credenciales: go run ./fundamentos/server -insecure-no-auth
+	var cfg config
+	flag.StringVar(&cfg.addr, "addr", ":8080", "dirección donde escuchará el servidor")
+	flag.StringVar(&cfg.storeKind, "store", "memory", "almacenamiento de usuarios: memory o file")
+	flag.StringVar(&cfg.dataPath, "data", "users.jsonl", "ruta del archivo de usuarios cuando -store=file")
+	flag.StringVar(&cfg.apiKeysPath, "api-keys", "", "archivo JSON con las claves de API")
+	flag.BoolVar(&cfg.insecureNoAuth, "insecure-no-auth", false, "inicia sin autenticación si no hay claves de API ni JWT_SECRET (solo para desarrollo)")
+	flag.Func("cors-origins", "orígenes separados por comas que pueden usar la API desde el navegador", func(value string) error {
+		for origin := range strings.SplitSeq(value, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.corsOrigins = append(cfg.corsOrigins, origin)
+			}
+		}
+		return nil
+	})
+	flag.DurationVar(&cfg.readTimeout, "read-timeout", 10*time.Second, "tiempo máximo para leer una solicitud completa")
+	flag.DurationVar(&cfg.writeTimeout, "write-timeout", 10*time.Second, "tiempo máximo para escribir una respuesta")
+	flag.DurationVar(&cfg.idleTimeout, "idle-timeout", 60*time.Second, "tiempo máximo de una conexión keep-alive inactiva")
+	flag.DurationVar(&cfg.drainDelay, "drain-delay", 0, "espera tras marcar el servidor como no listo y antes de apagarlo")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 15*time.Second, "tiempo máximo para terminar las solicitudes en curso")
+	flag.Parse()
+	cfg.jwtSecret = os.Getenv("JWT_SECRET")
+
+	// `run` contiene toda la lógica, así los `defer` se ejecutan antes de llamar a `os.Exit`.
+	if err := run(cfg); err != nil {
+		slog.Error("Error fatal del servidor", "error", err.Error())
+		os.Exit(1)
+	}
+}
+
+// run abre el almacenamiento, inicia el servidor y lo mantiene activo hasta recibir una señal de apagado.
+func run(cfg config) error {
+	store, err := openStore(cfg.storeKind, cfg.dataPath)
+	if err != nil {
+		return fmt.Errorf("no se pudo abrir el almacenamiento: %w", err)
+	}
+
+	// Si el almacenamiento necesita liberar recursos (como un archivo abierto), lo cerramos al terminar.
+	// Como el `defer` se ejecuta después del apagado, ninguna solicitud en curso usa un almacenamiento cerrado.
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	authn, err := newAuthenticator(cfg)
+	if err != nil {
+		return err
+	}
+
+	a := newApp(store, authn)
+
+	// Mostramos un mensaje en la consola cuando el servidor se inicia.
+	// `slog.Info()` es una función que registra un mensaje en la consola.
+	slog.Info("Iniciando servidor", "puerto", cfg.addr, "almacenamiento", cfg.storeKind)
+
+	//* Iniciamos el servidor.
+	// El servidor usa el manejador devuelto por `handler`: el multiplexor con los middlewares globales.
+	handler, err := a.handler(cfg)
+	if err != nil {
+		return err
+	}
+	return a.serve(newHTTPServer(cfg, handler), cfg)
+}
+
+// newApp crea la aplicación con el almacenamiento y el autenticador indicados (nil desactiva la autenticación).
+func newApp(store UserStore, authn auth.Authenticator) *app {
+	// Los cambios se hacen a través de `eventStore` para publicar un evento por cada uno.
+	events := newEventBroker(eventLogSize)
+	a := &app{
+		store:   &eventStore{UserStore: store, events: events},
+		authn:   authn,
+		events:  events,
+		health:  health.New(),
+		metrics: metrics.NewRegistry(),
+	}
+
+	// `/readyz` verifica que el almacenamiento de usuarios esté disponible.
+	a.health.Register("store", storeCheck(store))
+	// `/metrics` incluye las goroutines, la memoria y el recolector de basura del proceso.
+	metrics.RegisterRuntime(a.metrics)
+	return a
+}
+
+// openStore crea el almacenamiento de usuarios indicado por `kind`.
+func openStore(kind, path string) (UserStore, error) {
+	switch kind {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return NewFileStore(path)
+	default:
+		return nil, fmt.Errorf("almacenamiento desconocido %q", kind)
+	}
+}
+
+// newAuthenticator combina los autenticadores configurados: claves de API (-api-keys) y tokens JWT (JWT_SECRET).
+// Si no se configuró ninguno devuelve un error y el servidor no inicia, salvo con -insecure-no-auth,
+// en cuyo caso devuelve nil.
+func newAuthenticator(cfg config) (auth.Authenticator, error) {
+	var chain auth.Chain
+
+	if cfg.apiKeysPath != "" {
+		keys, err := auth.LoadAPIKeys(cfg.apiKeysPath)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, keys)
+	}
+
+	if cfg.jwtSecret != "" {
+		chain = append(chain, auth.NewJWT([]byte(cfg.jwtSecret)))
+	}
+
+	// Sin autenticadores cualquier cliente podría modificar y eliminar usuarios. Olvidar las credenciales
+	// no debe dejar la API abierta: solo la desactivamos si se pide de forma explícita con -insecure-no-auth,
+	// algo útil para aprender y probar, no para producción.
+	switch {
+	case len(chain) > 0:
+		return chain, nil
+	case cfg.insecureNoAuth:
+		slog.Warn("Se inició con -insecure-no-auth: la autenticación está desactivada y cualquier cliente puede modificar usuarios")
+		return nil, nil
+	default:
+		return nil, errors.New("no se configuraron claves de API (-api-keys) ni JWT_SECRET; " +
+			"usa -insecure-no-auth para iniciar sin autenticación")
+	}
+}
+
+// require devuelve el middleware de autenticación con los roles indicados (ver auth.Require).
+// Si la autenticación está desactivada (-insecure-no-auth), deja pasar todas las solicitudes.
+func (a *app) require(roles ...string) middleware.Middleware {
+	if a.authn == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return auth.Require(a.authn, roles...)
+}
+
+// principalScope separa las claves de idempotencia de cada cliente autenticado (ver middleware.Idempotency).
+// Sin autenticación todos los clientes comparten las mismas claves.
+func principalScope(r *http.Request) string {
+	principal, _ := auth.FromContext(r.Context())
+	return principal.Subject
+}
+
+// storeCheck devuelve la verificación de salud del almacenamiento.
+// Los almacenamientos que implementan `PingContext` (como el de archivo) se verifican con él;
+// el almacenamiento en memoria siempre está disponible.
+func storeCheck(store UserStore) health.Check {
+	if pinger, ok := store.(health.Pinger); ok {
+		return health.Ping(pinger)
+	}
+	return func(context.Context) error { return nil }
+}
+
+// handler envuelve el multiplexor de `routes` con los middlewares que se aplican a todas las solicitudes,
+// incluidas las que no coinciden con ninguna ruta (ver paquete middleware).
+func (a *app) handler(cfg config) (http.Handler, error) {
+	// Un origen mal escrito en -cors-origins es un error de configuración: el servidor no inicia.
+	cors, err := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins: cfg.corsOrigins,
+		AllowedMethods: []string{
+			http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		},
+		AllowedHeaders: []string{
+			"Content-Type", "Authorization", auth.APIKeyHeader, "If-Match", "If-None-Match", "Last-Event-ID",
+			middleware.RequestIDHeader, middleware.IdempotencyKeyHeader,
+		},
+		ExposedHeaders: []string{
+			"ETag", "Location", "Content-Disposition", middleware.RequestIDHeader,
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "Idempotent-Replayed",
+		},
+		MaxAge: corsMaxAge,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Los orígenes permitidos en CORS también pueden modificar datos. "*" no es un origen de confianza:
+	// permite leer las respuestas desde cualquier sitio, pero no enviar cambios con las cookies del usuario.
+	trusted := slices.DeleteFunc(slices.Clone(cfg.corsOrigins), func(origin string) bool { return origin == "*" })
+	csrf, err := middleware.CSRF(trusted...)
+	if err != nil {
+		return nil, err
+	}
+
+	return middleware.Chain(
+		// Asigna un X-Request-ID y registra una línea por solicitud con slog.
+		middleware.AccessLog(slog.Default()),
+		// Registra la cantidad y la duración de las solicitudes por ruta, método y clase de estado.
+		metrics.HTTP(a.metrics),
+		// Comprime con gzip o deflate las respuestas de más de 1 KB si el cliente lo acepta (Accept-Encoding).
+		middleware.Compress(middleware.CompressOptions{}),
+		// Agrega Content-Security-Policy, X-Content-Type-Options, Referrer-Policy y HSTS (solo con HTTPS).
+		middleware.SecurityHeaders(middleware.SecurityOptions{HSTSMaxAge: hstsMaxAge}),
+		// Responde los preflight antes del multiplexor, que respondería 405 a un OPTIONS.
+		cors,
+		// Convierte los pánicos de los manejadores en respuestas 500 y los registra con el X-Request-ID.
+		// Va después de SecurityHeaders y CORS para que el 500 conserve sus cabeceras.
+		middleware.Recover(slog.Default()),
+		// Va después de CORS para que el navegador pueda leer el 403 cuando CORS permite el origen (por ejemplo, con "*").
+		csrf,
+	)(a.routes()), nil
+}
+
+// routes registra las rutas de la API y devuelve el router listo para usarse (es un `http.Handler`).
+//
+// Los manejadores de usuarios devuelven un `error`. `httperror.HandlerFunc` los adapta a `http.Handler`
+// y envía cualquier error como `application/problem+json`, así todas las respuestas de error tienen el mismo formato.
+//
+// Las rutas se registran con `openapi.Router`, que exige documentar cada una. Con esa documentación
+// se genera el documento OpenAPI 3.1 que se sirve en `/openapi.json`. El multiplexor solo es accesible
+// a través del router: no hay forma de registrar aquí una ruta sin documentarla.
+func (a *app) routes() *openapi.Router {
 	// Nuevo multiplexor de solicitudes HTTP.
-	mux := http.NewServeMux()
+	router := openapi.NewRouter(http.NewServeMux())
 
 	// Define la ruta y el manejo de la petición.
-	mux.HandleFunc("/", handleRoot)
+	// `{$}` indica que solo coincide con "/" exactamente y no con cualquier ruta que empiece por "/".
+	router.HandleFunc("GET /{$}", handleRoot, openapi.Operation{
+		Summary:             "Mensaje de bienvenida",
+		Response:            "",
+		ResponseContentType: "text/plain",
+	})
+
+	// Endpoints de salud: `/healthz`, `/readyz` (responde 503 durante el apagado) y `/version`.
+	router.HandleFunc("GET /healthz", a.health.Liveness, openapi.Operation{
+		Summary:  "Indica si el proceso está vivo",
+		Response: health.Status{},
+	})
+	router.HandleFunc("GET /readyz", a.health.Readiness, openapi.Operation{
+		Summary:  "Indica si el servidor y sus dependencias pueden atender solicitudes",
+		Response: health.Status{},
+		Errors:   []int{http.StatusServiceUnavailable},
+	})
+	router.HandleFunc("GET /version", health.Version, openapi.Operation{
+		Summary:  "Información de compilación del servidor",
+		Response: health.BuildInfo{},
+	})
+
+	// Métricas en el formato de texto de Prometheus.
+	router.Handle("GET /metrics", a.metrics, openapi.Operation{
+		Summary:             "Métricas del servidor en formato Prometheus",
+		Response:            "",
+		ResponseContentType: "text/plain",
+	})
+
+	// El documento OpenAPI describe todas las rutas registradas en el router, incluida esta.
+	router.Handle("GET /openapi.json", router.Spec(openapi.Info{Title: "Users API", Version: "1.0.0"}), openapi.Operation{
+		Summary:  "Documento OpenAPI 3.1 de la API",
+		Response: openapi.Document{},
+	})
+
+	// Las rutas de lectura son abiertas. Las que modifican usuarios exigen un cliente autenticado
+	// y eliminar un usuario exige además el rol de administrador (ver paquete auth). Solo si el servidor
+	// se inició con -insecure-no-auth, `a.require` no exige nada y todas las rutas son abiertas.
+	// Cada grupo de rutas aplica su middleware a las rutas que registra (ver paquete middleware).
+	//
+	// Las rutas que responden un usuario o una página de usuarios tienen un tiempo límite (ver middleware.Timeout).
+	// Si se vence la respuesta es 503 y por eso todas documentan http.StatusServiceUnavailable.
+	// La importación, la exportación y los eventos no lo usan: sus respuestas pueden tardar más o se envían
+	// por partes, y se detienen cuando el cliente se desconecta.
+	timed := router.Group(middleware.Timeout(requestTimeout))
+	authenticated := timed.Group(a.require())
+	admin := timed.Group(a.require(auth.RoleAdmin))
+
+	// Limitamos cuántos usuarios puede crear cada IP. El límite se aplica antes de la autenticación,
+	// así tampoco se pueden probar claves de API sin límite.
+	createLimit := middleware.RateLimit(middleware.Limit{Requests: createRateLimit, Per: time.Minute, Burst: createRateBurst})
+
+	// Los reintentos de un POST con la misma cabecera Idempotency-Key repiten la primera respuesta
+	// en lugar de crear otro usuario. Van después de la autenticación: cada cliente tiene sus propias claves.
+	idempotencyKey := openapi.Header(middleware.IdempotencyKeyHeader, "Identificador único de la operación, para reintentarla sin duplicarla")
+	createIdempotency := middleware.Idempotency(middleware.IdempotencyOptions{TTL: idempotencyTTL, Scope: principalScope})
+	importIdempotency := middleware.Idempotency(middleware.IdempotencyOptions{
+		TTL:          idempotencyTTL,
+		MaxBodyBytes: maxImportBytes,
+		Scope:        principalScope,
+	})
 
 	// La solicitud debe ser de tipo 'POST' y la ruta debe ser '/users'.
 	// Esta sintaxis es solo válida a partir de la versión 1.22 de Go.
-	mux.HandleFunc("POST /users", createUser)
+	timed.Group(createLimit, a.require(), createIdempotency).Handle("POST /users", httperror.HandlerFunc(a.createUser), openapi.Operation{
+		Summary:    "Crea un usuario",
+		Request:    User{},
+		Response:   User{},
+		Status:     http.StatusCreated,
+		Parameters: []openapi.Parameter{idempotencyKey},
+		Errors: []int{
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict,
+			http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity,
+			http.StatusTooManyRequests, http.StatusServiceUnavailable,
+		},
+	})
+
+	// Lista los usuarios de forma paginada con los parámetros `?limit=` y `?offset=`.
+	// También permite filtrar, buscar y ordenar (ver query.go).
+	timed.Handle("GET /users", httperror.HandlerFunc(a.listUsers), openapi.Operation{
+		Summary:  "Lista los usuarios",
+		Response: UserPage{},
+		Parameters: []openapi.Parameter{
+			openapi.Query("name", "Nombre exacto, sin distinguir mayúsculas"),
+			openapi.Query("email_domain", "Dominio del email, por ejemplo example.com"),
+			openapi.Query("q", "Subcadena a buscar en el nombre o el email"),
+			openapi.Query("sort", "Campos de ordenamiento separados por comas (id, name, email); `-` para descendente"),
+			openapi.QueryInt("limit", fmt.Sprintf("Cantidad de usuarios por página (1-%d)", maxPageLimit)),
+			openapi.QueryInt("offset", "Cantidad de usuarios a omitir"),
+		},
+		Errors: []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+	})
+
+	// Importación y exportación masiva en JSON, NDJSON y CSV (ver bulk.go).
+	router.Group(a.require(), importIdempotency).Handle("POST /users:import", httperror.HandlerFunc(a.importUsers), openapi.Operation{
+		Summary:    "Importa usuarios desde un arreglo JSON, NDJSON (application/x-ndjson) o CSV (text/csv)",
+		Request:    []User{},
+		Response:   ImportReport{},
+		Parameters: []openapi.Parameter{idempotencyKey},
+		Errors: []int{
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity,
+		},
+	})
+	router.Handle("GET /users:export", httperror.HandlerFunc(a.exportUsers), openapi.Operation{
+		Summary:    "Exporta todos los usuarios ordenados por id",
+		Response:   []User{},
+		Parameters: []openapi.Parameter{openapi.Query("format", "Formato de la exportación: json (por defecto), ndjson o csv")},
+		Errors:     []int{http.StatusBadRequest},
+	})
+
+	// Flujo de Server-Sent Events con los cambios de usuarios.
+	// "/users/events" es más específico que "/users/{id}", así que el multiplexor lo elige primero.
+	router.Handle("GET /users/events", httperror.HandlerFunc(a.streamEvents), openapi.Operation{
+		Summary:             "Flujo de eventos (created, updated, deleted y reset) de los usuarios",
+		Response:            "",
+		ResponseContentType: "text/event-stream",
+		Parameters: []openapi.Parameter{openapi.Header("Last-Event-ID",
+			"Último id recibido, para reanudar el flujo. Si es de otro proceso o se perdieron eventos, el flujo empieza con un evento reset")},
+	})
 
 	// La solicitud debe ser de tipo 'GET' y la ruta debe ser '/users/{id}'.
 	// Recupera el parámetro de la ruta '{id}' de la solicitud que captura un valor dinámico.
-	mux.HandleFunc("GET /users/{id}", getUsers)
+	timed.Handle("GET /users/{id}", httperror.HandlerFunc(a.getUsers), openapi.Operation{
+		Summary:    "Obtiene un usuario",
+		Response:   User{},
+		Parameters: []openapi.Parameter{openapi.Header("If-None-Match", "ETag de la versión que ya tiene el cliente")},
+		Errors:     []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+	})
+
+	// 'PUT' reemplaza el usuario completo y 'PATCH' modifica solo los campos enviados.
+	ifMatch := openapi.Header("If-Match", "ETag de la versión que el cliente espera modificar")
+	authenticated.Handle("PUT /users/{id}", httperror.HandlerFunc(a.replaceUser), openapi.Operation{
+		Summary:    "Reemplaza un usuario",
+		Request:    User{},
+		Response:   User{},
+		Parameters: []openapi.Parameter{ifMatch},
+		Errors: []int{
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed,
+			http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusServiceUnavailable,
+		},
+	})
+	authenticated.Handle("PATCH /users/{id}", httperror.HandlerFunc(a.patchUser), openapi.Operation{
+		Summary:            "Modifica parcialmente un usuario (JSON Merge Patch)",
+		Request:            UserPatch{},
+		RequestContentType: "application/merge-patch+json",
+		Response:           User{},
+		Parameters:         []openapi.Parameter{ifMatch},
+		Errors: []int{
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed,
+			http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusServiceUnavailable,
+		},
+	})
 
 	// La solicitud debe ser de tipo 'DELETE' y la ruta debe ser '/users/{id}'.
-	mux.HandleFunc("DELETE /users/{id}", deleteUser)
-
-	// Mostramos un mensaje en la consola cuando el servidor se inicia.
-	// `slog.Info()` es una función que registra un mensaje en la consola.
-	slog.Info("Iniciando servidor", "puerto", ":8080")
-
-	//* Iniciamos el servidor.
-	// El primer argumento es la dirección o puerto donde escuchará el servidor.
-	// El segundo argumento es el manejador de solicitudes (en este caso, `mux`).
-	if err := http.ListenAndServe(":8080", mux); err != nil {
-		slog.Error("Error fatal del servidor", "error", err.Error())
-		os.Exit(1)
-	}
+	// Solo los administradores pueden eliminar usuarios.
+	admin.Handle("DELETE /users/{id}", httperror.HandlerFunc(a.deleteUser), openapi.Operation{
+		Summary:    "Elimina un usuario (requiere el rol admin)",
+		Status:     http.StatusNoContent,
+		Parameters: []openapi.Parameter{ifMatch},
+		Errors: []int{
+			http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+			http.StatusPreconditionFailed, http.StatusServiceUnavailable,
+		},
+	})
+
+	return router
 }
 
 // Controlador para manejar la ruta raíz.
@@ -110,133 +500,400 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	io.WriteString(w, "Hola Mundo!")
 }
 
-func createUser(w http.ResponseWriter, r *http.Request) {
-	// Creamos una instancia vacía de `User` para almacenar los datos del cuerpo de la solicitud.
-	// Go inicializa los campos de la estructura `User` a sus valores cero correspondientes.
-	var user User
-
-	// Crea un decodificador basado en el cuerpo (body) de la petición.
+func (a *app) createUser(w http.ResponseWriter, r *http.Request) error {
 	// Decodifica los datos de la petición y los almacena en la variable 'user'.
 	// La variable 'user' contendra todos los datos de que se especifique en el body de la petición.
-	// Evidentemente el cuerpo de la solicitud debe coincidir con nuestra estructura 'User'.
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		// Le pasamos la respuesta, el error y el estado de la petición en este caso 400.
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	// Evidentemente el cuerpo de la solicitud debe coincidir con nuestra estructura 'User':
+	// `DecodeJSON` rechaza campos desconocidos, cuerpos demasiado grandes y tipos de contenido distintos a JSON.
+	user, err := DecodeJSON[User](w, r)
+	if err != nil {
+		return err
 	}
 
-	//* Validaciones básicas.
-	// Verificamos que los campos requeridos no estén vacíos.
-	if user.Name == "" {
-		http.Error(w, "el nombre es requerido", http.StatusBadRequest)
-		return
+	//* Validaciones.
+	// Verificamos los campos según las etiquetas `validate` de la estructura `User`.
+	if err := validateBody(user); err != nil {
+		return err
 	}
 
-	if user.Email == "" {
-		http.Error(w, "el email es requerido", http.StatusBadRequest)
-		return
+	// Guardamos el usuario en el almacenamiento, que se encarga de asignarle un ID.
+	created, err := a.store.Create(r.Context(), user)
+	if err != nil {
+		return storeError(err)
 	}
 
-	// El ID del usuario se crea como el siguiente entero mayor al último ID guardado.
-	userID := len(users) + 1
+	// La cabecera `Location` indica la URL del nuevo recurso.
+	w.Header().Set("Location", fmt.Sprintf("/users/%d", created.ID))
+	w.Header().Set("ETag", etag(created))
 
-	mu.Lock()
-	defer mu.Unlock()
+	// Indicamos que la solicitud fue exitosa con un estado 201 y devolvemos el usuario con su ID.
+	RenderJSONStatus(w, http.StatusCreated, created)
+	return nil
+}
 
-	// Guardamos el usuario en la "base de datos".
-	users[userID] = user
+func (a *app) getUsers(w http.ResponseWriter, r *http.Request) error {
+	// Recuperamos el valor del parámetro '{id}' de la ruta de la solicitud y lo convertimos a entero.
+	// El método `PathValue` está disponible desde la versión 1.22 de Go.
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
 
-	// Indicamos que la solicitud fue exitosa con un estado 201.
-	w.WriteHeader(http.StatusCreated)
+	// Buscamos el usuario en el almacenamiento.
+	// Si el usuario no existe, `storeError` lo convierte en un error 404 (Not Found).
+	user, err := a.store.Get(r.Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+
+	// La ETag identifica la versión del usuario. Si el cliente ya tiene esa versión,
+	// respondemos 304 (Not Modified) sin cuerpo.
+	w.Header().Set("ETag", etag(user))
+	if notModified(r, user) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
 
-	// Respondemos con un mensaje de confirmación.
-	fmt.Fprintf(w, "el usuario %s fue creado exitosamente", user.Name)
+	// Devuelve los datos del usuario en formato JSON con el estado 200.
+	RenderJSON(w, user)
+	return nil
 }
 
-func getUsers(w http.ResponseWriter, r *http.Request) {
-	// Recuperamos el valor del parámetro '{id}' de la ruta de la solicitud.
-	// Este método `PathValue` está disponible desde la versión 1.22 de Go.
-	userId := r.PathValue("id")
+// Límite de `POST /users` por IP: `createRateLimit` usuarios por minuto, con ráfagas de hasta `createRateBurst`.
+const (
+	createRateLimit = 30
+	createRateBurst = 10
+)
+
+// idempotencyTTL es el tiempo que se guarda la respuesta de un POST con Idempotency-Key.
+const idempotencyTTL = 24 * time.Hour
+
+// requestTimeout es el tiempo límite de las rutas de usuarios. Es menor que el WriteTimeout por defecto
+// (10s) para que el cliente reciba el 503 antes de que el servidor cierre la conexión.
+const requestTimeout = 5 * time.Second
+
+const (
+	// hstsMaxAge indica al navegador que use HTTPS durante un año (en segundos).
+	hstsMaxAge = 365 * 24 * 60 * 60
+	// corsMaxAge es el tiempo que el navegador guarda la respuesta de un preflight.
+	corsMaxAge = 10 * time.Minute
+)
+
+// Valores por defecto y máximos de la paginación de `GET /users`.
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// UserPage es la respuesta de `GET /users`: una página de usuarios y los datos para pedir la siguiente.
+type UserPage struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
 
-	// Convertimos el ID de usuario de string a entero.
-	id, err := strconv.Atoi(userId)
+func (a *app) listUsers(w http.ResponseWriter, r *http.Request) error {
+	// Leemos los filtros, el orden y la paginación de la URL, por ejemplo:
+	// /users?email_domain=example.com&sort=name,-id&limit=10&offset=20
+	query := r.URL.Query()
 
+	userQuery, err := newUserQuery(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return httperror.BadRequest("parámetro de consulta inválido").WithFields(httperror.FieldError{
+			Field:  "sort",
+			Detail: err.Error(),
+		})
 	}
 
-	mu.RLock()
-	defer mu.RUnlock()
+	userQuery.Limit, err = queryInt(query.Get("limit"), defaultPageLimit)
+	if err != nil || userQuery.Limit < 1 || userQuery.Limit > maxPageLimit {
+		return httperror.BadRequest("parámetro de consulta inválido").WithFields(httperror.FieldError{
+			Field:  "limit",
+			Detail: fmt.Sprintf("debe ser un número entre 1 y %d", maxPageLimit),
+		})
+	}
 
-	// Buscamos el usuario en el mapa `users`.
-	user, ok := users[id]
+	userQuery.Offset, err = queryInt(query.Get("offset"), 0)
+	if err != nil || userQuery.Offset < 0 {
+		return httperror.BadRequest("parámetro de consulta inválido").WithFields(httperror.FieldError{
+			Field:  "offset",
+			Detail: "debe ser un número mayor o igual a 0",
+		})
+	}
 
-	if !ok {
-		// Si el usuario no existe, devolvemos un error 404 (Not Found).
-		http.Error(w, "el usuario no fue encontrado", http.StatusNotFound)
-		return
+	users, total, err := a.store.List(r.Context(), userQuery)
+	if err != nil {
+		return storeError(err)
 	}
 
-	// Configuramos la cabecera de la respuesta para indicar que es de tipo JSON.
-	w.Header().Set("Content-Type", "application/json")
+	RenderJSON(w, UserPage{Users: users, Total: total, Limit: userQuery.Limit, Offset: userQuery.Offset})
+	return nil
+}
+
+func (a *app) replaceUser(w http.ResponseWriter, r *http.Request) error {
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
+
+	// 'PUT' reemplaza el recurso completo, así que el cuerpo debe traer todos los campos.
+	user, err := DecodeJSON[User](w, r)
+	if err != nil {
+		return err
+	}
 
-	// Transformamos el usuario a JSON para mandarlo como respuesta al cliente.
-	data, err := json.Marshal(user)
+	if err := validateBody(user); err != nil {
+		return err
+	}
 
+	// Si el cliente envía If-Match, solo reemplazamos el usuario si sigue en la versión que leyó.
+	version, err := a.ifMatchVersion(r, id)
 	if err != nil {
-		// Si ocurre un error al codificar el JSON, devolvemos un error 500 (Internal Server Error).
-		// Es un error interno del servidor porque hasta este punto el usuario ingreso las datos correctamente.
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return err
+	}
+
+	updated, err := a.store.Update(r.Context(), id, user, version)
+	if err != nil {
+		return conditionalStoreError(r, err)
 	}
 
-	// Devuelve la respuesta al cliente con el estado 200.
-	w.WriteHeader(http.StatusOK)
-	// Devuelve los datos del usuario en formato JSON.
-	w.Write(data)
+	w.Header().Set("ETag", etag(updated))
+	RenderJSON(w, updated)
+	return nil
 }
 
-func deleteUser(w http.ResponseWriter, r *http.Request) {
-	// Recuperamos el valor del parámetro '{id}' de la ruta de la solicitud.
-	userId := r.PathValue("id")
+func (a *app) patchUser(w http.ResponseWriter, r *http.Request) error {
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
 
-	// Convertimos el ID de usuario de string a entero.
-	id, err := strconv.Atoi(userId)
+	// Leemos el parche completo sin decodificarlo, ya que necesitamos aplicarlo sobre el JSON del usuario actual.
+	// El tipo de contenido recomendado es `application/merge-patch+json`.
+	patch, err := DecodeJSON[json.RawMessage](w, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return err
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
+	// El parche se aplica sobre la versión que leímos y el almacenamiento solo la guarda si nadie la modificó
+	// mientras tanto; si no, dos PATCH concurrentes que cambian campos distintos se sobrescribirían.
+	// Si el cliente envía If-Match con una ETag, el parche solo se aplica sobre esa versión y un cambio
+	// concurrente es un 412. Sin ETag, volvemos a leer el usuario y aplicar el parche sobre la nueva versión.
+	for attempt := 1; ; attempt++ {
+		current, err := a.store.Get(r.Context(), id)
+		if err != nil {
+			return conditionalStoreError(r, err)
+		}
+
+		expected, err := ifMatch(r.Header.Get("If-Match"), current)
+		if err != nil {
+			return err
+		}
+
+		user, err := applyUserPatch(current, patch)
+		if err != nil {
+			return err
+		}
+
+		updated, err := a.store.Update(r.Context(), id, user, current.Version)
+		if errors.Is(err, ErrVersionMismatch) && expected == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		if err != nil {
+			return conditionalStoreError(r, err)
+		}
+
+		w.Header().Set("ETag", etag(updated))
+		RenderJSON(w, updated)
+		return nil
+	}
+}
 
-	// Verificamos si el usuario existe en el mapa.
-	if _, ok := users[id]; !ok {
-		http.Error(w, "el usuario no fue encontrado", http.StatusNotFound)
-		return
+// maxPatchAttempts es la cantidad de veces que `PATCH` sin If-Match vuelve a leer el usuario y aplicar
+// el parche cuando otra solicitud lo modificó al mismo tiempo.
+const maxPatchAttempts = 3
+
+// applyUserPatch aplica un parche de JSON Merge Patch sobre el usuario y valida el resultado.
+func applyUserPatch(current User, patch json.RawMessage) (User, error) {
+	original, err := json.Marshal(current)
+	if err != nil {
+		return User{}, err
 	}
 
-	// Eliminamos el par clave-valor del mapa por su id.
-	delete(users, id)
+	// Aplicamos el parche con la semántica de JSON Merge Patch y decodificamos el resultado.
+	merged, err := mergePatch(original, patch)
+	if err != nil {
+		return User{}, httperror.FromDecodeError(err)
+	}
+
+	// Decodificamos el resultado con las mismas reglas estrictas: un parche no puede agregar campos desconocidos.
+	var user User
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&user); err != nil {
+		return User{}, httperror.FromDecodeError(err)
+	}
+
+	// El resultado del parche debe seguir siendo un usuario válido.
+	if err := validateBody(user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (a *app) deleteUser(w http.ResponseWriter, r *http.Request) error {
+	// Recuperamos el valor del parámetro '{id}' de la ruta de la solicitud.
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
+
+	// Si el cliente envía If-Match, solo eliminamos el usuario si sigue en la versión que leyó.
+	version, err := a.ifMatchVersion(r, id)
+	if err != nil {
+		return err
+	}
+
+	// Eliminamos el usuario del almacenamiento verificando primero que exista.
+	if err := a.store.Delete(r.Context(), id, version); err != nil {
+		return conditionalStoreError(r, err)
+	}
 
 	// Indicamos que la petición fue exitosa con un estado 204.
 	// El código 204 indica que la acción fue exitosa, pero no es necesario devolver ninguna información.
 	w.WriteHeader(http.StatusNoContent)
+	return nil
 }
 
 // renderJSON es una función que se encarga de enviar una respuesta HTTP con un cuerpo
 // codificado en formato JSON.
 func RenderJSON[T any](w http.ResponseWriter, data T) {
-	// Configura la cabecera de la respuesta para indicar que el contenido es JSON.
-	w.Header().Set("Content-Type", "application/json")
+	RenderJSONStatus(w, http.StatusOK, data)
+}
 
-	encoder := json.NewEncoder(w)
-	// Codifica el valor 'T' en formato JSON.
-	if err := encoder.Encode(data); err != nil {
+// RenderJSONStatus es igual que RenderJSON, pero permite elegir el código de estado de la respuesta.
+func RenderJSONStatus[T any](w http.ResponseWriter, status int, data T) {
+	// Codifica el valor 'T' en formato JSON antes de escribir la respuesta.
+	// Así, si ocurre un error, todavía podemos cambiar el código de estado.
+	body, err := json.Marshal(data)
+	if err != nil {
 		// Si ocurre un error al codificar el JSON, se devuelve un error 500 (Internal Server Error)
 		// con el mensaje de error correspondiente.
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	// Configura la cabecera de la respuesta para indicar que el contenido es JSON.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// maxBodyBytes es el tamaño máximo del cuerpo de una solicitud JSON (1 MB).
+const maxBodyBytes = 1 << 20
+
+// DecodeJSON es el complemento de RenderJSON: decodifica el cuerpo JSON de la solicitud en un valor de tipo 'T'.
+//
+// A diferencia de `json.NewDecoder(r.Body).Decode()`, es estricto con lo que acepta:
+//   - El `Content-Type` debe ser JSON (`application/json` o `application/*+json`), si no responde 415.
+//   - El cuerpo no puede superar `maxBodyBytes`, si no responde 413.
+//   - Los campos que no existen en 'T' se rechazan con un 400.
+//   - El cuerpo debe contener un único valor JSON, sin datos adicionales al final (400).
+//
+// Los errores devueltos son *httperror.HTTPError, así que el manejador solo tiene que devolverlos.
+func DecodeJSON[T any](w http.ResponseWriter, r *http.Request) (T, error) {
+	var data T
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		return data, httperror.New(http.StatusUnsupportedMediaType, "el Content-Type debe ser application/json")
+	}
+
+	// `http.MaxBytesReader` devuelve un error al leer más de `maxBodyBytes` y le indica al servidor
+	// que cierre la conexión, así un cliente no puede enviarnos un cuerpo infinito.
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	// Los campos desconocidos suelen ser errores de escritura del cliente ("emial" en lugar de "email").
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&data); err != nil {
+		return data, httperror.FromDecodeError(err)
+	}
+
+	// Intentamos decodificar un segundo valor: si el cuerpo terminó, obtenemos `io.EOF`.
+	// Cualquier otro resultado significa que hay datos adicionales después del primer valor JSON.
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return data, httperror.BadRequest("el cuerpo de la solicitud debe contener un único valor JSON")
+	}
+
+	return data, nil
+}
+
+// isJSONContentType indica si el tipo de contenido es `application/json` o termina en `+json`
+// (por ejemplo `application/merge-patch+json`). Se ignoran parámetros como `charset=utf-8`.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
+
+// userID obtiene y convierte a entero el parámetro '{id}' de la ruta.
+func userID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, httperror.BadRequest(fmt.Sprintf("el id %q no es válido", r.PathValue("id")))
+	}
+	return id, nil
+}
+
+// queryInt convierte un parámetro de la URL a entero, usando `fallback` si el parámetro está vacío.
+func queryInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// validateBody valida el cuerpo decodificado de una solicitud con el paquete `validate`.
+// Si hay campos inválidos devuelve un error 400 con todos ellos como errores de campo.
+func validateBody(body any) error {
+	err := validate.Struct(body)
+
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	fields := make([]httperror.FieldError, len(errs))
+	for i, fieldErr := range errs {
+		fields[i] = httperror.FieldError{Field: fieldErr.Field, Detail: fieldErr.Message}
+	}
+	return httperror.BadRequest("el cuerpo de la solicitud contiene campos inválidos").WithFields(fields...)
+}
+
+// storeError traduce los errores del almacenamiento al HTTPError correspondiente.
+// Los errores desconocidos se devuelven sin cambios y se responden como 500.
+func storeError(err error) error {
+	switch {
+	// La operación no terminó dentro del tiempo límite, por ejemplo una consulta lenta a la base de datos.
+	// Respondemos 503, igual que middleware.Timeout: el manejador y el middleware ven vencer el mismo plazo
+	// casi a la vez y el cliente debe recibir el mismo estado sin importar cuál responde primero.
+	case errors.Is(err, context.DeadlineExceeded):
+		return httperror.New(http.StatusServiceUnavailable, "el almacenamiento no respondió a tiempo")
+	// El cliente se desconectó: no recibirá la respuesta, pero el registro de acceso muestra el motivo.
+	case errors.Is(err, context.Canceled):
+		return httperror.New(http.StatusServiceUnavailable, "la solicitud fue cancelada")
+	case errors.Is(err, ErrUserNotFound):
+		return httperror.Wrap(http.StatusNotFound, err)
+	case errors.Is(err, ErrEmailTaken):
+		return httperror.Wrap(http.StatusConflict, err)
+	case errors.Is(err, ErrVersionMismatch):
+		return httperror.Wrap(http.StatusPreconditionFailed, err)
+	default:
+		return err
+	}
 }
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/server/health"
+	"github.com/Mayer-04/logica-go/fundamentos/server/middleware"
 )
 
 /*
@@ -18,6 +23,8 @@ Este programa crea un servidor web que sirve archivos estáticos desde una carpe
 - El paquete "os" proporciona funciones para interactuar con el sistema operativo,
 como obtener el directorio de trabajo actual.
 - El paquete "slog" se utiliza para registrar eventos y mensajes informativos en el sistema de registro.
+- El paquete "health" (fundamentos/server/health) agrega los endpoints de salud `/healthz`, `/readyz` y `/version`.
+- El paquete "middleware" (fundamentos/server/middleware) comprime las respuestas con gzip o deflate.
 */
 
 func main() {
@@ -28,8 +35,20 @@ func main() {
 	publicPath := getPublicPath()
 	// Crea un servidor de archivos estáticos que servirá los archivos desde la carpeta 'public'.
 	fs := http.FileServer(http.Dir(publicPath))
+
+	// Creamos un multiplexor para combinar los archivos estáticos con los endpoints de salud.
+	mux := http.NewServeMux()
 	// Configura el manejador de solicitudes HTTP para servir archivos estáticos desde la ruta raíz (/).
-	http.Handle("/", fs)
+	mux.Handle("/", fs)
+
+	// Registra `/healthz`, `/readyz` y `/version`.
+	// `/readyz` verifica que la carpeta 'public' exista, ya que sin ella el servidor no tiene nada que servir.
+	checks := health.New()
+	checks.Register("public", func(ctx context.Context) error {
+		_, err := os.Stat(publicPath)
+		return err
+	})
+	checks.Routes(mux)
 	// Definimos el puerto en el que el servidor HTTP escuchará las solicitudes.
 	addr := ":5000"
 
@@ -42,18 +61,30 @@ func main() {
 		ReadTimeout: 5 * time.Second,
 		// Si el cliente no pudo recibir la respuesta completa del servidor, el servidor cierra la conexión.
 		WriteTimeout: 5 * time.Second,
-		Handler:      fs,
+		// Comprime los archivos de texto (HTML, CSS, JavaScript) si el navegador lo acepta.
+		// Las imágenes y otros archivos ya comprimidos se envían sin cambios.
+		Handler: middleware.Compress(middleware.CompressOptions{})(mux),
 	}
 
 	// Registra un mensaje en la consola utilizando slog cuando el servidor se inicia.
 	logger.Info("Iniciando servidor", "puerto", addr)
 
-	// Iniciamos el servidor web en el puerto especificado.
-	if err := server.ListenAndServe(); err != nil {
-		// Imprime el error y termina el programa si no se puede iniciar el servidor.
+	// Abrimos el puerto antes de marcar el servidor como listo: si está en uso, el programa termina
+	// sin que `/readyz` haya respondido 200. `ListenAndServe` abriría el puerto y serviría a la vez.
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
 		logger.Error("fallo al iniciar el servidor", "error", err)
 		os.Exit(1)
 	}
+	// Las conexiones que lleguen desde ahora esperan en la cola del puerto hasta que `Serve` las acepte.
+	checks.SetReady(true)
+
+	// Iniciamos el servidor web sobre el puerto ya abierto.
+	if err := server.Serve(ln); err != nil {
+		// Imprime el error y termina el programa si el servidor se detiene.
+		logger.Error("el servidor se detuvo", "error", err)
+		os.Exit(1)
+	}
 }
 
 // getCurrentDirectory obtiene el directorio de trabajo actual.
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+/*
+* Almacenamiento de usuarios
+- Los manejadores no deberían saber dónde se guardan los usuarios (memoria, archivo, base de datos, etc.).
+- La interfaz `UserStore` define el comportamiento que necesitan los manejadores y cada
+implementación decide cómo y dónde guardar los datos.
+- Gracias a esto podemos elegir el almacenamiento al iniciar el servidor y probar los
+manejadores con cualquiera de las implementaciones.
+
+* Contexto
+Todas las operaciones reciben el contexto de la solicitud (`r.Context()`), igual que `database/sql`
+con `QueryContext` o pgx. Si el cliente se desconecta o se vence el tiempo límite de la ruta
+(ver middleware.Timeout), el contexto se cancela y la operación termina con `ctx.Err()`
+en lugar de seguir trabajando para nadie (ver fundamentos/context).
+*/
+
+// ErrUserNotFound se devuelve cuando el usuario solicitado no existe en el almacenamiento.
+var ErrUserNotFound = errors.New("el usuario no fue encontrado")
+
+// ErrEmailTaken se devuelve cuando otro usuario ya utiliza el mismo email.
+var ErrEmailTaken = errors.New("ya existe un usuario con ese email")
+
+// ErrVersionMismatch se devuelve cuando el usuario fue modificado por otra solicitud
+// desde que el cliente leyó la versión que espera modificar.
+var ErrVersionMismatch = errors.New("el usuario fue modificado por otra solicitud")
+
+// UserStore define las operaciones de almacenamiento que necesitan los manejadores de usuarios.
+type UserStore interface {
+	// Create guarda un nuevo usuario y lo devuelve con el ID asignado.
+	// Los IDs aumentan siempre y nunca se reutilizan, aunque se eliminen usuarios.
+	Create(ctx context.Context, user User) (User, error)
+	// Get devuelve el usuario con el ID indicado o `ErrUserNotFound` si no existe.
+	Get(ctx context.Context, id int) (User, error)
+	// List devuelve los usuarios que cumplen los filtros de `query`, ordenados y paginados,
+	// junto con el número total de usuarios que cumplen los filtros.
+	List(ctx context.Context, query UserQuery) ([]User, int, error)
+	// Update reemplaza el usuario con el ID indicado, incrementa su versión y devuelve el usuario guardado.
+	// Si `version` no es 0 y no coincide con la versión actual, devuelve `ErrVersionMismatch`.
+	Update(ctx context.Context, id int, user User, version int) (User, error)
+	// Delete elimina el usuario con el ID indicado o devuelve `ErrUserNotFound` si no existe.
+	// Si `version` no es 0 y no coincide con la versión actual, devuelve `ErrVersionMismatch`.
+	Delete(ctx context.Context, id int, version int) error
+}
+
+// * Simulación de una base de datos en memoria.
+// memoryStore almacena los usuarios en un mapa protegido por un `sync.RWMutex`.
+// La clave es un entero que representa el ID del usuario, y el valor es un estructura `User`.
+// Los datos se pierden al detener el servidor.
+type memoryStore struct {
+	mu    sync.RWMutex
+	users map[int]User
+	// emails es un índice email → ID para verificar que un email no esté en uso sin recorrer
+	// todos los usuarios. Se actualiza junto con `users` en cada escritura.
+	emails map[string]int
+	// ids contiene los IDs de los usuarios ordenados de menor a mayor. Permite responder las consultas
+	// ordenadas por ID (como los lotes de la exportación) sin copiar ni ordenar todos los usuarios.
+	ids []int
+	// lastID es el último ID asignado. Solo aumenta, así un usuario eliminado
+	// nunca provoca que un usuario nuevo reciba (y sobrescriba) un ID existente.
+	lastID int
+}
+
+// NewMemoryStore crea un almacenamiento de usuarios en memoria vacío.
+func NewMemoryStore() *memoryStore {
+	return &memoryStore{users: make(map[int]User), emails: make(map[string]int)}
+}
+
+func (s *memoryStore) Create(ctx context.Context, user User) (User, error) {
+	// En memoria las operaciones son inmediatas: basta con no empezarlas si el contexto ya terminó.
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTakenLocked(user.Email, 0) {
+		return User{}, ErrEmailTaken
+	}
+
+	// Asignamos el ID dentro del bloqueo para que dos solicitudes concurrentes nunca reciban el mismo.
+	s.lastID++
+	user.ID = s.lastID
+	user.Version = 1
+	s.setLocked(user)
+	return user, nil
+}
+
+// nextID devuelve el ID que recibirá el próximo usuario creado.
+func (s *memoryStore) nextID() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextIDLocked()
+}
+
+// nextIDLocked es igual que nextID, pero debe llamarse con `mu` bloqueado.
+func (s *memoryStore) nextIDLocked() int {
+	// El ID del usuario se crea como el siguiente entero mayor al último ID asignado.
+	return s.lastID + 1
+}
+
+// emailTakenLocked indica si algún usuario distinto de `exceptID` ya utiliza el email.
+// Debe llamarse con `mu` bloqueado.
+func (s *memoryStore) emailTakenLocked(email string, exceptID int) bool {
+	id, ok := s.emails[email]
+	return ok && id != exceptID
+}
+
+// setLocked guarda el usuario y actualiza el índice de emails. Debe llamarse con `mu` bloqueado.
+func (s *memoryStore) setLocked(user User) {
+	if previous, ok := s.users[user.ID]; !ok {
+		s.insertIDLocked(user.ID)
+	} else if s.emails[previous.Email] == user.ID {
+		delete(s.emails, previous.Email)
+	}
+	s.users[user.ID] = user
+	s.emails[user.Email] = user.ID
+}
+
+// deleteLocked elimina el usuario y su email del índice. Debe llamarse con `mu` bloqueado.
+func (s *memoryStore) deleteLocked(id int) {
+	if user, ok := s.users[id]; ok {
+		if s.emails[user.Email] == id {
+			delete(s.emails, user.Email)
+		}
+		delete(s.users, id)
+		if i, found := slices.BinarySearch(s.ids, id); found {
+			s.ids = slices.Delete(s.ids, i, i+1)
+		}
+	}
+}
+
+// insertIDLocked agrega `id` a la lista ordenada de IDs. Debe llamarse con `mu` bloqueado.
+func (s *memoryStore) insertIDLocked(id int) {
+	// Los IDs nuevos siempre son mayores que los anteriores: el caso común es agregarlo al final.
+	if len(s.ids) == 0 || id > s.ids[len(s.ids)-1] {
+		s.ids = append(s.ids, id)
+		return
+	}
+	i, _ := slices.BinarySearch(s.ids, id)
+	s.ids = slices.Insert(s.ids, i, id)
+}
+
+// emailTaken es igual que emailTakenLocked, pero bloquea `mu` para lectura.
+func (s *memoryStore) emailTaken(email string, exceptID int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.emailTakenLocked(email, exceptID)
+}
+
+// put guarda el usuario con el ID indicado, reemplazándolo si ya existía.
+// También avanza la secuencia de IDs para que nunca se asigne un ID menor o igual a `id`.
+func (s *memoryStore) put(id int, user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.ID = id
+	s.setLocked(user)
+	s.lastID = max(s.lastID, id)
+}
+
+func (s *memoryStore) Get(ctx context.Context, id int) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, id int, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.currentLocked(id, version); err != nil {
+		return err
+	}
+
+	// Eliminamos el par clave-valor del mapa por su id (y su email del índice).
+	s.deleteLocked(id)
+	return nil
+}
+
+// remove elimina el usuario con el ID indicado sin verificar su versión.
+func (s *memoryStore) remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteLocked(id)
+}
+
+// current devuelve el usuario con el ID indicado verificando que tenga la versión esperada.
+// Una versión 0 significa que el cliente no espera ninguna versión en particular.
+func (s *memoryStore) current(id int, version int) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.currentLocked(id, version)
+}
+
+// currentLocked es igual que current, pero debe llamarse con `mu` bloqueado.
+func (s *memoryStore) currentLocked(id int, version int) (User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	if version != 0 && user.Version != version {
+		return User{}, ErrVersionMismatch
+	}
+	return user, nil
+}
+
+func (s *memoryStore) List(ctx context.Context, query UserQuery) ([]User, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.RLock()
+	if query.byIDOnly() {
+		// Sin filtros y en el orden de los IDs, la página se lee directamente de la lista ordenada.
+		defer s.mu.RUnlock()
+		page, total := s.pageByIDLocked(query)
+		return page, total, nil
+	}
+	// Copiamos los usuarios para filtrarlos y ordenarlos sin mantener el bloqueo.
+	users := slices.Collect(maps.Values(s.users))
+	s.mu.RUnlock()
+
+	// Filtrar y ordenar muchos usuarios puede tardar: volvemos a verificar antes de hacerlo.
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	page, total := applyUserQuery(users, query)
+	return page, total, nil
+}
+
+// pageByIDLocked devuelve la página de `query` (que no tiene filtros ni otro orden que el ID) buscando
+// `AfterID` en la lista ordenada de IDs. Debe llamarse con `mu` bloqueado.
+func (s *memoryStore) pageByIDLocked(query UserQuery) ([]User, int) {
+	first, _ := slices.BinarySearch(s.ids, query.AfterID+1)
+	total := len(s.ids) - first
+	start := min(first+query.Offset, len(s.ids))
+	end := min(start+query.Limit, len(s.ids))
+
+	page := make([]User, 0, end-start)
+	for _, id := range s.ids[start:end] {
+		page = append(page, s.users[id])
+	}
+	return page, total
+}
+
+func (s *memoryStore) Update(ctx context.Context, id int, user User, version int) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// La verificación de la versión y la escritura ocurren dentro del mismo bloqueo,
+	// así ninguna otra solicitud puede modificar el usuario entre ambas.
+	current, err := s.currentLocked(id, version)
+	if err != nil {
+		return User{}, err
+	}
+	if s.emailTakenLocked(user.Email, id) {
+		return User{}, ErrEmailTaken
+	}
+
+	user.ID = id
+	user.Version = current.Version + 1
+	s.setLocked(user)
+	return user, nil
+}
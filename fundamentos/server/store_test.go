@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+	"github.com/Mayer-04/logica-go/fundamentos/server/middleware"
+)
+
+// TestMain descarta los registros del servidor (cada solicitud escribe una línea) para que
+// la salida de `go test` muestre solo los resultados.
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(m.Run())
+}
+
+// testStores devuelve un almacenamiento nuevo de cada tipo, para ejecutar la misma prueba sobre ambos.
+func testStores(t *testing.T) map[string]UserStore {
+	t.Helper()
+
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "users.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	t.Cleanup(func() { fileStore.Close() })
+
+	return map[string]UserStore{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+}
+
+// newTestHandler devuelve el manejador completo del servidor (middlewares globales y rutas)
+// sobre `store`, sin autenticación.
+func newTestHandler(t *testing.T, store UserStore) http.Handler {
+	t.Helper()
+
+	handler, err := newApp(store, nil).handler(config{})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	return handler
+}
+
+// TestCreateUserConcurrentIDs crea usuarios en paralelo y verifica que cada uno recibe un ID distinto.
+// Ejecutar con `go test -race` para detectar además accesos concurrentes sin sincronizar.
+func TestCreateUserConcurrentIDs(t *testing.T) {
+	const n = 50
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			handler := newTestHandler(t, store)
+
+			var wg sync.WaitGroup
+			ids := make([]int, n)
+			for i := range n {
+				wg.Go(func() {
+					body := fmt.Sprintf(`{"name":"Usuario %d","email":"usuario%d@example.com"}`, i, i)
+					req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+					req.Header.Set("Content-Type", "application/json")
+					// Cada solicitud viene de otra IP para no alcanzar el límite de POST /users.
+					req.RemoteAddr = fmt.Sprintf("10.0.%d.%d:1234", i/256, i%256)
+
+					rec := httptest.NewRecorder()
+					handler.ServeHTTP(rec, req)
+					if rec.Code != http.StatusCreated {
+						t.Errorf("solicitud %d: estado %d, se esperaba 201: %s", i, rec.Code, rec.Body)
+						return
+					}
+
+					var created User
+					if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+						t.Errorf("solicitud %d: cuerpo inválido: %v", i, err)
+						return
+					}
+					if want := fmt.Sprintf("/users/%d", created.ID); rec.Header().Get("Location") != want {
+						t.Errorf("solicitud %d: Location = %q, se esperaba %q", i, rec.Header().Get("Location"), want)
+					}
+					ids[i] = created.ID
+				})
+			}
+			wg.Wait()
+
+			seen := make(map[int]int, n)
+			for i, id := range ids {
+				if other, ok := seen[id]; ok {
+					t.Errorf("las solicitudes %d y %d recibieron el mismo ID %d", other, i, id)
+				}
+				seen[id] = i
+			}
+		})
+	}
+}
+
+// TestFileStoreReloadKeepsIDs verifica que al reabrir el archivo se recuperan los usuarios
+// y que la secuencia de IDs continúa después del mayor ID guardado.
+func TestFileStoreReloadKeepsIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	ctx := t.Context()
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	for i := range 3 {
+		if _, err := store.Create(ctx, User{Name: "Usuario", Email: fmt.Sprintf("u%d@example.com", i)}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if err := store.Delete(ctx, 3, 0); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	store.Close()
+
+	// Simulamos una escritura interrumpida: la última línea queda incompleta.
+	appendRaw(t, path, `{"op":"put","id":4,"us`)
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore al reabrir: %v", err)
+	}
+	defer store.Close()
+
+	_, total, err := store.List(ctx, UserQuery{Limit: 10})
+	if err != nil || total != 2 {
+		t.Fatalf("List: total = %d, err = %v; se esperaban 2 usuarios", total, err)
+	}
+	created, err := store.Create(ctx, User{Name: "Nuevo", Email: "nuevo@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID != 4 {
+		t.Errorf("ID = %d, se esperaba 4 (el ID 3 eliminado no se reutiliza)", created.ID)
+	}
+}
+
+// TestFileStoreRejectsPutWithoutUser verifica que un registro "put" sin usuario es un error y no un pánico.
+func TestFileStoreRejectsPutWithoutUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	appendRaw(t, path, `{"op":"put","id":1}`+"\n")
+
+	if store, err := NewFileStore(path); err == nil {
+		store.Close()
+		t.Fatal("se esperaba un error al abrir un archivo con un registro put sin usuario")
+	}
+}
+
+// appendRaw agrega `data` al final del archivo tal cual, sin agregar un salto de línea.
+func appendRaw(t *testing.T, path, data string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := io.WriteString(file, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStoreEmailIndex verifica que al cambiar el email o eliminar un usuario su email anterior queda libre.
+func TestStoreEmailIndex(t *testing.T) {
+	ctx := t.Context()
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ana, err := store.Create(ctx, User{Name: "Ana", Email: "ana@example.com"})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			bob, err := store.Create(ctx, User{Name: "Bob", Email: "bob@example.com"})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			if _, err := store.Update(ctx, bob.ID, User{Name: "Bob", Email: "ana@example.com"}, 0); !errors.Is(err, ErrEmailTaken) {
+				t.Errorf("Update con el email de otro usuario: err = %v, se esperaba ErrEmailTaken", err)
+			}
+			if _, err := store.Update(ctx, ana.ID, User{Name: "Ana", Email: "ana@example.org"}, 0); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			if err := store.Delete(ctx, bob.ID, 0); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+
+			for _, email := range []string{"ana@example.com", "bob@example.com"} {
+				if _, err := store.Create(ctx, User{Name: "Nuevo", Email: email}); err != nil {
+					t.Errorf("Create con %s: %v, el email debía estar libre", email, err)
+				}
+			}
+			if _, err := store.Create(ctx, User{Name: "Otra", Email: "ana@example.org"}); !errors.Is(err, ErrEmailTaken) {
+				t.Errorf("Create con el email actual de Ana: err = %v, se esperaba ErrEmailTaken", err)
+			}
+		})
+	}
+}
+
+// slowStore simula un almacenamiento lento. Si `wait` es true, Get espera a que termine el contexto
+// de la solicitud; si no, falla de inmediato con su propio plazo, como una consulta con statement_timeout.
+type slowStore struct {
+	UserStore
+	wait bool
+}
+
+func (s slowStore) Get(ctx context.Context, id int) (User, error) {
+	if !s.wait {
+		return User{}, fmt.Errorf("consulta cancelada: %w", context.DeadlineExceeded)
+	}
+	<-ctx.Done()
+	return User{}, ctx.Err()
+}
+
+// TestStoreTimeoutStatus verifica que un almacenamiento que no responde a tiempo produce siempre un 503,
+// lo responda middleware.Timeout o el manejador con storeError. Ambos ven vencer el mismo plazo casi a la vez:
+// sin importar cuál responde primero, el estado debe ser el mismo que documenta OpenAPI.
+func TestStoreTimeoutStatus(t *testing.T) {
+	for _, wait := range []bool{true, false} {
+		a := newApp(slowStore{UserStore: NewMemoryStore(), wait: wait}, nil)
+		handler := middleware.Timeout(time.Millisecond)(httperror.HandlerFunc(a.getUsers))
+
+		for range 20 {
+			req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			req.SetPathValue("id", "1")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("wait = %v: estado %d, se esperaba 503: %s", wait, rec.Code, rec.Body)
+			}
+		}
+	}
+}
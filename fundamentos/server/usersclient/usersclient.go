@@ -0,0 +1,319 @@
+// Package usersclient es un cliente tipado para la API de usuarios de fundamentos/server.
+package usersclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mayer-04/logica-go/fundamentos/errors/httperror"
+)
+
+/*
+* Cliente HTTP tipado
+En lugar de trabajar con `[]byte` y códigos de estado en cada llamada (como `RequestAPI` en
+fundamentos/concurrency/sync/waitgroup), el cliente ofrece un método por operación que recibe
+y devuelve tipos de Go:
+
+	client := usersclient.New("http://localhost:8080")
+	user, err := client.Create(ctx, usersclient.User{Name: "Mayer", Email: "mayer@example.com"})
+
+* Autenticación:
+Crear y modificar usuarios requiere credenciales y eliminarlos requiere el rol admin:
+
+	client := usersclient.New("http://localhost:8080", usersclient.WithAPIKey("mi-clave"))
+
+* Errores:
+- Si la API responde con un error, el método devuelve un *Error con el cuerpo `application/problem+json`.
+- Con `errors.As` podemos leer el código de estado y los errores de cada campo.
+
+* Reintentos:
+- Solo se reintentan las operaciones idempotentes (GET, PUT y DELETE): repetirlas produce el mismo resultado.
+- POST no se reintenta porque podría crear el usuario dos veces.
+- Se reintenta ante errores de red, respuestas cortadas a la mitad y respuestas 502, 503 y 504, esperando
+cada vez el doble de tiempo (retroceso exponencial) y respetando la cancelación del contexto.
+- Los demás errores (un 4xx, un 500 o una respuesta que no es JSON) no se reintentan: se repetirían igual.
+*/
+
+// User es un usuario de la API.
+type User struct {
+	ID      int    `json:"id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+}
+
+// Page es una página de usuarios devuelta por List.
+type Page struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// ListOptions son los filtros, el orden y la paginación de List. Los campos vacíos se omiten.
+type ListOptions struct {
+	Name        string
+	EmailDomain string
+	Search      string
+	Sort        string
+	Limit       int
+	Offset      int
+}
+
+// Error es la respuesta de error de la API (`application/problem+json`).
+type Error struct {
+	httperror.Problem
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("usersclient: %d %s: %s", e.Status, e.Title, e.Detail)
+}
+
+// Client llama a la API de usuarios.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	// auth agrega las credenciales a cada solicitud. Nil si el cliente es anónimo.
+	auth func(header http.Header)
+}
+
+// Option configura el cliente (patrón de opciones funcionales, ver fundamentos/designpatterns/functionalopts).
+type Option func(*Client)
+
+// WithHTTPClient reemplaza el `http.Client` usado para las solicitudes.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey autentica las solicitudes con una clave de API (cabecera `X-API-Key`).
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.auth = func(header http.Header) { header.Set("X-API-Key", key) }
+	}
+}
+
+// WithToken autentica las solicitudes con un token firmado (`Authorization: Bearer <token>`).
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.auth = func(header http.Header) { header.Set("Authorization", "Bearer "+token) }
+	}
+}
+
+// WithRetries cambia la cantidad máxima de reintentos y la espera inicial entre ellos.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New crea un cliente para la API que se encuentra en `baseURL`, por ejemplo "http://localhost:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(c) // aplicar cada opción
+	}
+
+	return c
+}
+
+// Create crea un usuario y lo devuelve con el ID y la versión asignados.
+func (c *Client) Create(ctx context.Context, user User) (User, error) {
+	var created User
+	err := c.do(ctx, http.MethodPost, "/users", nil, user, &created)
+	return created, err
+}
+
+// Get devuelve el usuario con el ID indicado.
+func (c *Client) Get(ctx context.Context, id int) (User, error) {
+	var user User
+	err := c.do(ctx, http.MethodGet, userPath(id), nil, nil, &user)
+	return user, err
+}
+
+// List devuelve una página de usuarios.
+func (c *Client) List(ctx context.Context, opts ListOptions) (Page, error) {
+	query := url.Values{}
+	setIfNotEmpty(query, "name", opts.Name)
+	setIfNotEmpty(query, "email_domain", opts.EmailDomain)
+	setIfNotEmpty(query, "q", opts.Search)
+	setIfNotEmpty(query, "sort", opts.Sort)
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	path := "/users"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var page Page
+	err := c.do(ctx, http.MethodGet, path, nil, nil, &page)
+	return page, err
+}
+
+// Update reemplaza el usuario `user.ID`. Si `user.Version` no es 0, se envía como If-Match
+// y la API responde 412 si otro cliente modificó el usuario desde que se leyó.
+func (c *Client) Update(ctx context.Context, user User) (User, error) {
+	header := http.Header{}
+	if user.Version != 0 {
+		header.Set("If-Match", strconv.Quote(strconv.Itoa(user.Version)))
+	}
+
+	var updated User
+	err := c.do(ctx, http.MethodPut, userPath(user.ID), header, user, &updated)
+	return updated, err
+}
+
+// Delete elimina el usuario con el ID indicado.
+func (c *Client) Delete(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, userPath(id), nil, nil, nil)
+}
+
+// do envía la solicitud, reintentando las idempotentes, y decodifica la respuesta en `out` (si no es nil).
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, in, out any) error {
+	// Codificamos el cuerpo una sola vez para poder reenviarlo en cada reintento.
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("usersclient: no se pudo codificar la solicitud: %w", err)
+		}
+	}
+
+	retries := 0
+	if method != http.MethodPost {
+		retries = c.maxRetries
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, path, header, body, out)
+		if attempt >= retries || !retryable(err) {
+			return err
+		}
+
+		// Esperamos antes de reintentar, a menos que el contexto se cancele primero.
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
+}
+
+// send realiza un único intento de la solicitud.
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("usersclient: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.auth != nil {
+		c.auth(req.Header)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("usersclient: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("usersclient: no se pudo decodificar la respuesta: %w", err)
+	}
+	return nil
+}
+
+// decodeError convierte una respuesta de error en un *Error.
+// Si el cuerpo no es `application/problem+json`, se construye el error a partir del código de estado.
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{Problem: httperror.Problem{
+		Status: resp.StatusCode,
+		Title:  http.StatusText(resp.StatusCode),
+	}}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &apiErr.Problem); err != nil {
+		apiErr.Detail = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
+
+// retryable indica si vale la pena reintentar después de `err`.
+func retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Status {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// Si el contexto ya terminó, otro intento fallaría igual.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// Solo reintentamos los errores de red (conexión rechazada, cortada, etc.) y una respuesta que se cortó
+	// mientras la leíamos. Un error al codificar o decodificar se repetiría en cada intento.
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func userPath(id int) string {
+	return "/users/" + strconv.Itoa(id)
+}
+
+func setIfNotEmpty(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
@@ -0,0 +1,191 @@
+// Package validate valida estructuras a partir de etiquetas (tags) `validate:"..."`.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+* Validación declarativa con etiquetas
+En lugar de escribir un `if` por cada campo, describimos las reglas junto a la definición del campo:
+
+	type User struct {
+		Name  string `json:"name" validate:"required,max=100"`
+		Email string `json:"email" validate:"required,email"`
+	}
+
+Con el paquete `reflect` recorremos los campos, leemos la etiqueta `validate` y aplicamos cada regla.
+
+* Reglas disponibles:
+- required: El campo no puede tener su valor cero ("" para strings, 0 para números, etc.).
+- email: El campo debe ser una dirección de correo válida según `net/mail.ParseAddress`.
+- min=N: Longitud mínima para strings (en caracteres) o valor mínimo para números.
+- max=N: Longitud máxima para strings (en caracteres) o valor máximo para números.
+
+* Comportamiento:
+- Se reportan TODOS los campos inválidos a la vez, no solo el primero.
+- Por cada campo se reporta solo la primera regla que falla.
+- Un campo vacío que no es `required` no se valida con el resto de reglas.
+- El nombre del campo en los errores es el de la etiqueta `json`, que es el que conoce el cliente.
+*/
+
+// FieldError describe por qué un campo no es válido.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Errors agrupa los errores de todos los campos inválidos. Implementa la interfaz `error`.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Struct valida los campos de la estructura `v` (o de un puntero a estructura) según sus etiquetas.
+// Devuelve nil si todos los campos son válidos o un valor de tipo `Errors` en caso contrario.
+//
+// Si una etiqueta contiene una regla desconocida, Struct entra en pánico: es un error de programación,
+// no un error de los datos recibidos.
+func Struct(v any) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: se esperaba una estructura, se recibió %s", value.Kind()))
+	}
+
+	var errs Errors
+	valueType := value.Type()
+
+	for i := range valueType.NumField() {
+		field := valueType.Field(i)
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		if message := checkField(value.Field(i), strings.Split(tag, ",")); message != "" {
+			errs = append(errs, FieldError{Field: fieldName(field), Message: message})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkField aplica las reglas sobre el valor de un campo y devuelve el mensaje de la primera que falla.
+func checkField(value reflect.Value, rules []string) string {
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		if name == "required" {
+			if value.IsZero() {
+				return "es requerido"
+			}
+			continue
+		}
+
+		// Los campos opcionales vacíos no se validan con el resto de reglas.
+		if value.IsZero() {
+			return ""
+		}
+
+		check, ok := rulesByName[name]
+		if !ok {
+			panic(fmt.Sprintf("validate: regla desconocida %q", name))
+		}
+		if message := check(value, param); message != "" {
+			return message
+		}
+	}
+	return ""
+}
+
+// rule valida un valor con el parámetro de la etiqueta (por ejemplo "100" en `max=100`).
+// Devuelve un mensaje si el valor no cumple la regla o una cadena vacía si la cumple.
+type rule func(value reflect.Value, param string) string
+
+var rulesByName = map[string]rule{
+	"email": checkEmail,
+	"min":   checkMin,
+	"max":   checkMax,
+}
+
+func checkEmail(value reflect.Value, _ string) string {
+	// `mail.ParseAddress` acepta formatos como "Mayer <mayer@example.com>".
+	// Para un campo de email solo aceptamos la dirección, sin nombre.
+	addr, err := mail.ParseAddress(value.String())
+	if err != nil || addr.Address != value.String() {
+		return "debe ser un email válido"
+	}
+	return ""
+}
+
+func checkMin(value reflect.Value, param string) string {
+	limit := mustParseInt(param)
+	if value.Kind() == reflect.String {
+		if utf8.RuneCountInString(value.String()) < limit {
+			return fmt.Sprintf("debe tener al menos %d caracteres", limit)
+		}
+		return ""
+	}
+	if number(value) < float64(limit) {
+		return fmt.Sprintf("debe ser mayor o igual a %d", limit)
+	}
+	return ""
+}
+
+func checkMax(value reflect.Value, param string) string {
+	limit := mustParseInt(param)
+	if value.Kind() == reflect.String {
+		if utf8.RuneCountInString(value.String()) > limit {
+			return fmt.Sprintf("debe tener como máximo %d caracteres", limit)
+		}
+		return ""
+	}
+	if number(value) > float64(limit) {
+		return fmt.Sprintf("debe ser menor o igual a %d", limit)
+	}
+	return ""
+}
+
+// number convierte un valor numérico de cualquier tamaño a float64 para poder compararlo.
+func number(value reflect.Value) float64 {
+	switch {
+	case value.CanInt():
+		return float64(value.Int())
+	case value.CanUint():
+		return float64(value.Uint())
+	case value.CanFloat():
+		return value.Float()
+	default:
+		panic(fmt.Sprintf("validate: min/max no se puede aplicar al tipo %s", value.Kind()))
+	}
+}
+
+func mustParseInt(param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("validate: parámetro inválido %q", param))
+	}
+	return n
+}
+
+// fieldName devuelve el nombre del campo en la etiqueta `json` o, si no tiene, el nombre en Go.
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}